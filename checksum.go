@@ -2,7 +2,13 @@ package going
 
 import (
 	"crypto/md5"
+	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"strconv"
+
+	"github.com/mlu1109/going/migrsrc"
 )
 
 type Checksum func(s string) (string, error)
@@ -12,3 +18,22 @@ func DefaultChecksumFn(s string) (string, error) {
 	h := hex.EncodeToString(sum[:])
 	return h, nil
 }
+
+func SHA256ChecksumFn(s string) (string, error) {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func CRC32ChecksumFn(s string) (string, error) {
+	return strconv.FormatUint(uint64(crc32.ChecksumIEEE([]byte(s))), 10), nil
+}
+
+// checksumOf checksums the content of m. Go migrations without content are
+// checksummed by version and description instead of all sharing the checksum
+// of no content.
+func (g *G) checksumOf(m *migrsrc.Migration) (string, error) {
+	if m.Func != nil && m.Content == "" {
+		return g.checksum(fmt.Sprintf("go:%s:%s", m.Version, m.Description))
+	}
+	return g.checksum(m.Content)
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mlu1109/going"
+	"github.com/mlu1109/going/datasrc/postgres"
+	"github.com/mlu1109/going/logger"
+	"github.com/mlu1109/going/migrsrc/filesys"
+	"github.com/mlu1109/going/version"
+)
+
+type command struct {
+	usage   string
+	help    string
+	minArgs int
+	maxArgs int
+	run     func(ctx context.Context, g *going.G, args []string) (interface{}, error)
+	// dryRun is set by commands that only log their plan with -dry-run.
+	dryRun bool
+	// runLocal is set instead of run by commands that need no database.
+	runLocal func(o *options, args []string, stderr io.Writer) (interface{}, error)
+}
+
+// unlimited is the maxArgs of commands with any number of arguments.
+const unlimited = -1
+
+var commandNames = []string{"new", "migrate", "migrate-to", "undo", "plan", "info", "validate", "baseline", "repair", "clean"}
+
+var commands = map[string]*command{
+	"new": {
+		usage:    "new [-timestamp] [-undo] <description>",
+		help:     "create an empty migration in the first directory",
+		minArgs:  1,
+		maxArgs:  unlimited,
+		runLocal: scaffold,
+	},
+	"migrate": {
+		usage:  "migrate",
+		help:   "apply pending migrations",
+		dryRun: true,
+		run: func(ctx context.Context, g *going.G, args []string) (interface{}, error) {
+			return nil, g.MigrateContext(ctx)
+		},
+	},
+	"migrate-to": {
+		usage:   "migrate-to <version>",
+		help:    "apply or undo migrations until version is the latest applied",
+		minArgs: 1,
+		maxArgs: 1,
+		dryRun:  true,
+		run: func(ctx context.Context, g *going.G, args []string) (interface{}, error) {
+			return nil, g.MigrateToContext(ctx, version.Version(args[0]))
+		},
+	},
+	"undo": {
+		usage:   "undo [n]",
+		help:    "undo the latest n migrations, 1 by default",
+		maxArgs: 1,
+		dryRun:  true,
+		run: func(ctx context.Context, g *going.G, args []string) (interface{}, error) {
+			n := 1
+			if len(args) > 0 {
+				var err error
+				n, err = strconv.Atoi(args[0])
+				if err != nil {
+					return nil, &usageError{fmt.Errorf("invalid number of migrations: %s", args[0])}
+				}
+			}
+			return nil, g.UndoContext(ctx, n)
+		},
+	},
+	"plan": {
+		usage:  "plan",
+		help:   "show the migrations that migrate would apply",
+		dryRun: true,
+		run: func(ctx context.Context, g *going.G, args []string) (interface{}, error) {
+			plan, err := g.PlanContext(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return plan.Steps, nil
+		},
+	},
+	"info": {
+		usage: "info",
+		help:  "show the state of every migration",
+		run: func(ctx context.Context, g *going.G, args []string) (interface{}, error) {
+			return g.InfoContext(ctx)
+		},
+	},
+	"validate": {
+		usage: "validate",
+		help:  "compare local and applied migrations",
+		run: func(ctx context.Context, g *going.G, args []string) (interface{}, error) {
+			return nil, g.ValidateContext(ctx)
+		},
+	},
+	"baseline": {
+		usage:   "baseline <version> [description]",
+		help:    "mark migrations up to version as applied",
+		minArgs: 1,
+		maxArgs: 2,
+		run: func(ctx context.Context, g *going.G, args []string) (interface{}, error) {
+			description := "baseline"
+			if len(args) > 1 {
+				description = args[1]
+			}
+			return nil, g.BaselineContext(ctx, version.Version(args[0]), description)
+		},
+	},
+	"repair": {
+		usage: "repair",
+		help:  "remove failed migrations and update changed checksums",
+		run: func(ctx context.Context, g *going.G, args []string) (interface{}, error) {
+			return nil, g.RepairContext(ctx)
+		},
+	},
+	"clean": {
+		usage: "clean",
+		help:  "drop the managed schema, requires -create-schema",
+		run: func(ctx context.Context, g *going.G, args []string) (interface{}, error) {
+			return nil, g.CleanContext(ctx)
+		},
+	},
+}
+
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *usageError) Unwrap() error {
+	return e.err
+}
+
+func run(ctx context.Context, args []string, environ []string, stdout, stderr io.Writer) int {
+	o, args, err := parseOptions(args, environ, stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "going: %s\n", err)
+		return exitUsage
+	}
+	if len(args) == 0 {
+		fmt.Fprintf(stderr, "going: no command given, run going -h for usage\n")
+		return exitUsage
+	}
+	name, args := args[0], args[1:]
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "going: unknown command: %s\n", name)
+		return exitUsage
+	}
+	if len(args) < cmd.minArgs || (cmd.maxArgs != unlimited && len(args) > cmd.maxArgs) {
+		fmt.Fprintf(stderr, "going: usage: going [flags] %s\n", cmd.usage)
+		return exitUsage
+	}
+	if o.dryRun && !cmd.dryRun {
+		fmt.Fprintf(stderr, "going: -dry-run is not supported by %s\n", name)
+		return exitUsage
+	}
+	if cmd.runLocal != nil {
+		res, err := cmd.runLocal(o, args, stderr)
+		return report(o, stdout, stderr, name, res, err)
+	}
+	if o.DSN == "" {
+		fmt.Fprintf(stderr, "going: no connection string given, set -dsn or GOING_DSN\n")
+		return exitUsage
+	}
+	l := logger.Std(log.New(stderr, "", log.LstdFlags))
+	g, closeDB, err := newGoing(ctx, o, l)
+	if err != nil {
+		return report(o, stdout, stderr, name, nil, err)
+	}
+	defer closeDB()
+	res, err := cmd.run(ctx, g, args)
+	return report(o, stdout, stderr, name, res, err)
+}
+
+func newGoing(ctx context.Context, o *options, l logger.Logger) (*going.G, func() error, error) {
+	db, err := o.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	err = db.PingContext(ctx)
+	if err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	ds, err := o.DS(db, postgres.WithLogger(l))
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	goingOptions := append(o.Options(), going.WithLogger(l))
+	if o.dryRun {
+		goingOptions = append(goingOptions, going.WithDryRun())
+	}
+	g, err := going.New(o.MS(), ds, goingOptions...)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return g, db.Close, nil
+}
+
+func scaffold(o *options, args []string, stderr io.Writer) (interface{}, error) {
+	fs := flag.NewFlagSet("going new", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	timestamp := fs.Bool("timestamp", false, "version by the current UTC time instead of the next major version")
+	undo := fs.Bool("undo", false, "also create an undo migration")
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, &usageError{err}
+	}
+	if fs.NArg() == 0 {
+		return nil, &usageError{errors.New("no description given")}
+	}
+	var options []filesys.ScaffoldOption
+	if *timestamp {
+		options = append(options, filesys.WithTimestamp(time.Now().UTC()))
+	}
+	if *undo {
+		options = append(options, filesys.WithUndo())
+	}
+	return o.MS().Scaffold(strings.Join(fs.Args(), " "), options...)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mlu1109/going/config"
+)
+
+type options struct {
+	*config.Config
+	json   bool
+	dryRun bool
+}
+
+// configFileNames are looked for in the working directory unless -config or
+// GOING_CONFIG is given.
+var configFileNames = []string{"going.yaml", "going.yml", "going.toml"}
+
+// parseOptions parses flags from args over the config file and the
+// environment, and returns the remaining arguments.
+func parseOptions(args []string, environ []string, stderr io.Writer) (*options, []string, error) {
+	o := &options{}
+	var configFile, profile, dsn, schema, table, dirs string
+	var createSchema, recursive, outOfOrder bool
+	fs := flag.NewFlagSet("going", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&configFile, "config", "", "config file, going.yaml, going.yml or going.toml by default (GOING_CONFIG)")
+	fs.StringVar(&profile, "profile", "", "profile of the config file (GOING_PROFILE)")
+	fs.StringVar(&dsn, "dsn", "", "Postgres connection string (GOING_DSN)")
+	fs.StringVar(&schema, "schema", "", "schema of the history table, public by default (GOING_SCHEMA)")
+	fs.BoolVar(&createSchema, "create-schema", false, "create the schema and allow clean to drop it (GOING_CREATE_SCHEMA)")
+	fs.StringVar(&table, "table", "", "name of the history table (GOING_TABLE)")
+	fs.StringVar(&dirs, "dir", "", "comma separated migration directories, migrations by default (GOING_DIR)")
+	fs.BoolVar(&recursive, "recursive", false, "load migrations from subdirectories (GOING_RECURSIVE)")
+	fs.BoolVar(&outOfOrder, "out-of-order", false, "apply migrations with a lower version than the latest applied one (GOING_OUT_OF_ORDER)")
+	fs.BoolVar(&o.json, "json", false, "print results as JSON")
+	fs.BoolVar(&o.dryRun, "dry-run", false, "log the plan of migrate, migrate-to or undo instead of applying it")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: going [flags] <command> [arguments]\n\nCommands:\n")
+		for _, name := range commandNames {
+			fmt.Fprintf(stderr, "  %-40s %s\n", commands[name].usage, commands[name].help)
+		}
+		fmt.Fprintf(stderr, "\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, nil, err
+	}
+	if configFile == "" {
+		configFile = getenv(environ, "GOING_CONFIG")
+	}
+	if configFile == "" {
+		configFile = findConfigFile()
+	}
+	loadOptions := []config.Option{config.WithEnviron(environ)}
+	if profile != "" {
+		loadOptions = append(loadOptions, config.WithProfile(profile))
+	}
+	if configFile != "" {
+		o.Config, err = config.Load(configFile, loadOptions...)
+	} else if profile != "" {
+		err = errors.New("a profile requires a config file")
+	} else {
+		o.Config, err = config.FromEnv(loadOptions...)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "dsn":
+			o.DSN = dsn
+		case "schema":
+			o.Schema = schema
+		case "create-schema":
+			o.CreateSchema = createSchema
+		case "table":
+			o.Table = table
+		case "dir":
+			o.Locations = splitList(dirs)
+		case "recursive":
+			o.Recursive = recursive
+		case "out-of-order":
+			o.OutOfOrder = outOfOrder
+		}
+	})
+	if len(o.Locations) == 0 {
+		return nil, nil, errors.New("no migration directory given")
+	}
+	return o, fs.Args(), nil
+}
+
+func findConfigFile() string {
+	for _, fn := range configFileNames {
+		if _, err := os.Stat(fn); err == nil {
+			return fn
+		}
+	}
+	return ""
+}
+
+func getenv(environ []string, key string) string {
+	var v string
+	for _, kv := range environ {
+		if strings.HasPrefix(kv, key+"=") {
+			v = kv[len(key)+1:]
+		}
+	}
+	return v
+}
+
+func splitList(s string) []string {
+	var res []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
@@ -0,0 +1,32 @@
+// Command going applies migrations from directories of SQL files to a
+// Postgres database.
+//
+// Usage:
+//
+//	going [flags] <command> [arguments]
+//
+// Settings are read from going.yaml, going.yml or going.toml in the working
+// directory, see package config. Environment variables such as GOING_DSN
+// override the file and flags override both. Run going -h for details.
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+)
+
+// Exit codes
+const (
+	exitOK         = 0
+	exitFailure    = 1
+	exitUsage      = 2
+	exitValidation = 3
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Environ(), os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mlu1109/going"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOptions(t *testing.T) {
+
+	t.Run("Flags take precedence over environment and config file", func(t *testing.T) {
+		// Given
+		dir := t.TempDir()
+		configFile := filepath.Join(dir, "going.yaml")
+		err := os.WriteFile(configFile, []byte("dsn: postgres://file\nschema: file_schema\ntable: file_table\n"), 0644)
+		assert.Nil(t, err)
+		env := []string{
+			"GOING_CONFIG=" + configFile,
+			"GOING_DSN=postgres://env",
+			"GOING_SCHEMA=env_schema",
+			"GOING_DIR=a, b",
+		}
+		// When
+		o, args, err := parseOptions([]string{"-schema", "flag_schema", "info"}, env, &bytes.Buffer{})
+		// Then
+		assert.Nil(t, err)
+		assert.Equal(t, "postgres://env", o.DSN)
+		assert.Equal(t, "flag_schema", o.Schema)
+		assert.Equal(t, "file_table", o.Table)
+		assert.Equal(t, []string{"a", "b"}, o.Locations)
+		assert.Equal(t, []string{"info"}, args)
+	})
+
+	t.Run("Profile without config file is an error", func(t *testing.T) {
+		// When
+		_, _, err := parseOptions([]string{"-profile", "ci", "info"}, nil, &bytes.Buffer{})
+		// Then
+		assert.NotNil(t, err)
+	})
+}
+
+func TestRun(t *testing.T) {
+
+	t.Run("Unknown command is a usage error", func(t *testing.T) {
+		// Given
+		var stdout, stderr bytes.Buffer
+		env := []string{"GOING_DSN=postgres://localhost"}
+		// When
+		code := run(context.Background(), []string{"frobnicate"}, env, &stdout, &stderr)
+		// Then
+		assert.Equal(t, exitUsage, code)
+		assert.Contains(t, stderr.String(), "unknown command: frobnicate")
+	})
+
+	t.Run("Wrong number of arguments is a usage error", func(t *testing.T) {
+		// Given
+		var stdout, stderr bytes.Buffer
+		env := []string{"GOING_DSN=postgres://localhost"}
+		// When
+		code := run(context.Background(), []string{"migrate-to"}, env, &stdout, &stderr)
+		// Then
+		assert.Equal(t, exitUsage, code)
+	})
+
+	t.Run("Dry run of a command without dry run support is a usage error", func(t *testing.T) {
+		for _, args := range [][]string{{"clean"}, {"baseline", "1"}, {"repair"}} {
+			// Given
+			var stdout, stderr bytes.Buffer
+			env := []string{"GOING_DSN=postgres://localhost"}
+			// When
+			code := run(context.Background(), append([]string{"-dry-run"}, args...), env, &stdout, &stderr)
+			// Then
+			assert.Equal(t, exitUsage, code, args)
+			assert.Contains(t, stderr.String(), "-dry-run is not supported by "+args[0])
+		}
+	})
+}
+
+func TestRunNew(t *testing.T) {
+	// Given
+	dir := t.TempDir()
+	var stdout, stderr bytes.Buffer
+	env := []string{"GOING_DIR=" + dir}
+	// When
+	code := run(context.Background(), []string{"-json", "new", "-undo", "create", "users"}, env, &stdout, &stderr)
+	// Then ...
+	assert.Equal(t, exitOK, code)
+	// ... the files were created without a database
+	files := []string{filepath.Join(dir, "V1__create_users.sql"), filepath.Join(dir, "U1__create_users.sql")}
+	for _, f := range files {
+		assert.FileExists(t, f)
+	}
+	var res result
+	assert.Nil(t, json.Unmarshal(stdout.Bytes(), &res))
+	assert.Equal(t, files, res.Files)
+}
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, exitOK, exitCode(nil))
+	assert.Equal(t, exitFailure, exitCode(errors.New("boom")))
+	validationErr := &going.ValidationError{Errors: []error{errors.New("mismatch")}}
+	assert.Equal(t, exitValidation, exitCode(fmt.Errorf("migrate: %w", validationErr)))
+	// migrate fails with the first validation error
+	assert.Equal(t, exitValidation, exitCode(&going.ChecksumMismatchError{Version: "1"}))
+}
+
+func TestReportJSON(t *testing.T) {
+	// Given
+	var stdout bytes.Buffer
+	info := []*going.MigrationInfo{{Version: "1", Description: "first", State: going.StateApplied}}
+	// When
+	code := report(&options{json: true}, &stdout, &bytes.Buffer{}, "info", info, nil)
+	// Then
+	assert.Equal(t, exitOK, code)
+	assert.JSONEq(t, `{
+		"command": "info",
+		"ok": true,
+		"migrations": [{"version": "1", "description": "first", "kind": "versioned", "state": "applied"}]
+	}`, stdout.String())
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/mlu1109/going"
+	"github.com/mlu1109/going/migrsrc"
+	"github.com/mlu1109/going/version"
+)
+
+type result struct {
+	Command    string          `json:"command"`
+	OK         bool            `json:"ok"`
+	Error      string          `json:"error,omitempty"`
+	Migrations []migrationJSON `json:"migrations,omitempty"`
+	Files      []string        `json:"files,omitempty"`
+}
+
+type migrationJSON struct {
+	Version         string `json:"version,omitempty"`
+	Description     string `json:"description"`
+	Kind            string `json:"kind"`
+	State           string `json:"state,omitempty"`
+	Checksum        string `json:"checksum,omitempty"`
+	AppliedChecksum string `json:"applied_checksum,omitempty"`
+}
+
+// report prints the result of a command and returns the exit code.
+func report(o *options, stdout, stderr io.Writer, name string, res interface{}, err error) int {
+	code := exitCode(err)
+	if o.json {
+		r := &result{Command: name, OK: err == nil}
+		if err != nil {
+			r.Error = err.Error()
+		}
+		r.Migrations = toJSON(res)
+		r.Files, _ = res.([]string)
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if encErr := enc.Encode(r); encErr != nil {
+			fmt.Fprintf(stderr, "going: %s\n", encErr)
+			return exitFailure
+		}
+		return code
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "going: %s failed: %s\n", name, err)
+		return code
+	}
+	switch res := res.(type) {
+	case []*going.MigrationInfo:
+		w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tDESCRIPTION\tSTATE\tCHECKSUM")
+		for _, i := range res {
+			checksum := i.LocalChecksum
+			if checksum == "" {
+				checksum = i.AppliedChecksum
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", displayVersion(i.Version), i.Description, i.State, checksum)
+		}
+		w.Flush()
+	case []*going.PlanStep:
+		if len(res) == 0 {
+			fmt.Fprintln(stdout, "Nothing to apply")
+			return code
+		}
+		w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tDESCRIPTION\tCHECKSUM")
+		for _, s := range res {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", displayVersion(s.Version), s.Description, s.Checksum)
+		}
+		w.Flush()
+	case []string:
+		for _, f := range res {
+			fmt.Fprintf(stdout, "Created %s\n", f)
+		}
+	default:
+		fmt.Fprintf(stdout, "%s: ok\n", name)
+	}
+	return code
+}
+
+func exitCode(err error) int {
+	var usageErr *usageError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &usageErr), errors.Is(err, version.ErrInvalid):
+		return exitUsage
+	case isValidationError(err):
+		return exitValidation
+	default:
+		return exitFailure
+	}
+}
+
+// isValidationError reports if err is from Validate or one of its errors,
+// which migrate, migrate-to and undo fail with.
+func isValidationError(err error) bool {
+	var validationErr *going.ValidationError
+	var checksumErr *going.ChecksumMismatchError
+	var descriptionErr *going.DescriptionMismatchError
+	var missingErr *going.MissingLocalMigrationError
+	var outOfOrderErr *going.OutOfOrderError
+	var failedErr *going.FailedMigrationError
+	return errors.As(err, &validationErr) ||
+		errors.As(err, &checksumErr) ||
+		errors.As(err, &descriptionErr) ||
+		errors.As(err, &missingErr) ||
+		errors.As(err, &outOfOrderErr) ||
+		errors.As(err, &failedErr)
+}
+
+func toJSON(res interface{}) []migrationJSON {
+	var ms []migrationJSON
+	switch res := res.(type) {
+	case []*going.MigrationInfo:
+		ms = make([]migrationJSON, 0, len(res))
+		for _, i := range res {
+			ms = append(ms, migrationJSON{
+				Version:         i.Version.String(),
+				Description:     i.Description,
+				Kind:            kindName(i.Kind),
+				State:           string(i.State),
+				Checksum:        i.LocalChecksum,
+				AppliedChecksum: i.AppliedChecksum,
+			})
+		}
+	case []*going.PlanStep:
+		ms = make([]migrationJSON, 0, len(res))
+		for _, s := range res {
+			ms = append(ms, migrationJSON{
+				Version:     s.Version.String(),
+				Description: s.Description,
+				Kind:        kindName(s.Kind),
+				Checksum:    s.Checksum,
+			})
+		}
+	}
+	return ms
+}
+
+func displayVersion(v version.Version) string {
+	if v == "" {
+		return "R"
+	}
+	return v.String()
+}
+
+func kindName(k migrsrc.Kind) string {
+	if k == migrsrc.KindRepeatable {
+		return "repeatable"
+	}
+	return "versioned"
+}
@@ -0,0 +1,326 @@
+// Package config builds going from a going.yaml or going.toml file, e.g.
+//
+//	dsn: postgres://localhost/app?sslmode=disable
+//	locations: [migrations]
+//	schema: app
+//	createSchema: true
+//	placeholders:
+//	  owner: app
+//	profiles:
+//	  ci:
+//	    dsn: postgres://ci/app
+//
+// Profiles override the top level values. Environment variables override both.
+package config
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/mlu1109/going"
+	"github.com/mlu1109/going/datasrc/postgres"
+	"github.com/mlu1109/going/migrsrc/filesys"
+	"gopkg.in/yaml.v3"
+
+	_ "github.com/lib/pq"
+)
+
+type Config struct {
+	DSN string `yaml:"dsn" toml:"dsn"`
+	// Locations are migration directories, relative to the config file.
+	Locations           []string          `yaml:"locations" toml:"locations"`
+	Recursive           bool              `yaml:"recursive" toml:"recursive"`
+	Schema              string            `yaml:"schema" toml:"schema"`
+	CreateSchema        bool              `yaml:"createSchema" toml:"createSchema"`
+	Table               string            `yaml:"table" toml:"table"`
+	Placeholders        map[string]string `yaml:"placeholders" toml:"placeholders"`
+	Checksum            string            `yaml:"checksum" toml:"checksum"`
+	OutOfOrder          bool              `yaml:"outOfOrder" toml:"outOfOrder"`
+	CommitEachMigration bool              `yaml:"commitEachMigration" toml:"commitEachMigration"`
+}
+
+// Environment variables overriding the values of a config file. Placeholders
+// are set by GOING_PLACEHOLDERS_<name>.
+const (
+	EnvProfile             = "GOING_PROFILE"
+	EnvDSN                 = "GOING_DSN"
+	EnvLocations           = "GOING_DIR"
+	EnvRecursive           = "GOING_RECURSIVE"
+	EnvSchema              = "GOING_SCHEMA"
+	EnvCreateSchema        = "GOING_CREATE_SCHEMA"
+	EnvTable               = "GOING_TABLE"
+	EnvPlaceholdersPrefix  = "GOING_PLACEHOLDERS_"
+	EnvChecksum            = "GOING_CHECKSUM"
+	EnvOutOfOrder          = "GOING_OUT_OF_ORDER"
+	EnvCommitEachMigration = "GOING_COMMIT_EACH_MIGRATION"
+)
+
+var Checksums = map[string]going.Checksum{
+	"md5":    going.DefaultChecksumFn,
+	"sha256": going.SHA256ChecksumFn,
+	"crc32":  going.CRC32ChecksumFn,
+}
+
+var ErrUnknownProfile = errors.New("unknown profile")
+var ErrUnknownChecksum = errors.New("unknown checksum")
+var ErrUnsupportedFormat = errors.New("unsupported config file format")
+
+// Default returns the configuration used without a config file.
+func Default() *Config {
+	return &Config{
+		Locations: []string{"migrations"},
+		Schema:    postgres.DefaultSchema,
+		Table:     postgres.DefaultHistoryTableName,
+		Checksum:  "md5",
+	}
+}
+
+// Load reads a .yaml, .yml or .toml file over the defaults, then applies the
+// selected profile and the environment.
+func Load(path string, options ...Option) (*Config, error) {
+	o := newOpts(options)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := Default()
+	c.Locations = nil
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		err = c.decodeYAML(content, o.profile)
+	case ".toml":
+		err = c.decodeTOML(content, o.profile)
+	default:
+		err = ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(c.Locations) == 0 {
+		c.Locations = Default().Locations
+	}
+	for i, l := range c.Locations {
+		if !filepath.IsAbs(l) {
+			c.Locations[i] = filepath.Join(filepath.Dir(path), l)
+		}
+	}
+	err = c.applyEnv(o.environ)
+	if err != nil {
+		return nil, err
+	}
+	return c, c.validate()
+}
+
+// FromEnv returns the defaults with the environment applied.
+func FromEnv(options ...Option) (*Config, error) {
+	o := newOpts(options)
+	c := Default()
+	err := c.applyEnv(o.environ)
+	if err != nil {
+		return nil, err
+	}
+	return c, c.validate()
+}
+
+func (c *Config) decodeYAML(content []byte, profile string) error {
+	var file struct {
+		Config   `yaml:",inline"`
+		Profiles map[string]yaml.Node `yaml:"profiles"`
+	}
+	file.Config = *c
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	dec.KnownFields(true)
+	err := dec.Decode(&file)
+	if err != nil {
+		return err
+	}
+	*c = file.Config
+	if profile == "" {
+		return nil
+	}
+	node, ok := file.Profiles[profile]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownProfile, profile)
+	}
+	// Node.Decode ignores unknown fields, so the profile is decoded again
+	content, err = yaml.Marshal(&node)
+	if err != nil {
+		return err
+	}
+	dec = yaml.NewDecoder(bytes.NewReader(content))
+	dec.KnownFields(true)
+	return dec.Decode(c)
+}
+
+func (c *Config) decodeTOML(content []byte, profile string) error {
+	var file struct {
+		Config
+		Profiles map[string]toml.Primitive `toml:"profiles"`
+	}
+	file.Config = *c
+	md, err := toml.Decode(string(content), &file)
+	if err != nil {
+		return err
+	}
+	*c = file.Config
+	if profile != "" {
+		p, ok := file.Profiles[profile]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownProfile, profile)
+		}
+		err = md.PrimitiveDecode(p, c)
+		if err != nil {
+			return err
+		}
+	}
+	for _, key := range md.Undecoded() {
+		if len(key) > 1 && key[0] == "profiles" && key[1] != profile {
+			continue
+		}
+		return fmt.Errorf("unknown field %s", key)
+	}
+	return nil
+}
+
+func (c *Config) applyEnv(environ []string) error {
+	var err error
+	for _, kv := range environ {
+		k, v := kv, ""
+		if i := strings.IndexByte(kv, '='); i >= 0 {
+			k, v = kv[:i], kv[i+1:]
+		}
+		if v == "" {
+			continue
+		}
+		switch {
+		case k == EnvDSN:
+			c.DSN = v
+		case k == EnvLocations:
+			c.Locations = splitList(v)
+		case k == EnvRecursive:
+			c.Recursive, err = strconv.ParseBool(v)
+		case k == EnvSchema:
+			c.Schema = v
+		case k == EnvCreateSchema:
+			c.CreateSchema, err = strconv.ParseBool(v)
+		case k == EnvTable:
+			c.Table = v
+		case k == EnvChecksum:
+			c.Checksum = v
+		case k == EnvOutOfOrder:
+			c.OutOfOrder, err = strconv.ParseBool(v)
+		case k == EnvCommitEachMigration:
+			c.CommitEachMigration, err = strconv.ParseBool(v)
+		case strings.HasPrefix(k, EnvPlaceholdersPrefix):
+			if c.Placeholders == nil {
+				c.Placeholders = make(map[string]string)
+			}
+			c.Placeholders[strings.TrimPrefix(k, EnvPlaceholdersPrefix)] = v
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", k, err)
+		}
+	}
+	return nil
+}
+
+func (c *Config) validate() error {
+	if _, ok := Checksums[c.Checksum]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownChecksum, c.Checksum)
+	}
+	if len(c.Locations) == 0 {
+		return errors.New("no migration location given")
+	}
+	return nil
+}
+
+// Open opens the database of DSN with its search path set to Schema, unless
+// DSN sets one, since the history table is not schema qualified.
+func (c *Config) Open() (*sql.DB, error) {
+	dsn, err := withSearchPath(c.DSN, c.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid connection string: %w", err)
+	}
+	return sql.Open("postgres", dsn)
+}
+
+func (c *Config) MS() *filesys.MS {
+	var options []filesys.Option
+	for _, l := range c.Locations[1:] {
+		options = append(options, filesys.WithPath(l))
+	}
+	if c.Recursive {
+		options = append(options, filesys.WithRecursive())
+	}
+	return filesys.New(c.Locations[0], options...)
+}
+
+// DS returns a postgres.DS for db after setting up its history table.
+func (c *Config) DS(db *sql.DB, options ...postgres.Option) (*postgres.DS, error) {
+	options = append([]postgres.Option{
+		postgres.WithDB(db),
+		postgres.WithSchema(c.Schema, c.CreateSchema),
+		postgres.WithHistoryTable(c.Table),
+	}, options...)
+	return postgres.Open(options...)
+}
+
+// Options returns the going options of the config. Options given to New after
+// them take precedence.
+func (c *Config) Options() []going.Option {
+	options := []going.Option{going.WithChecksum(Checksums[c.Checksum])}
+	if len(c.Placeholders) > 0 {
+		options = append(options, going.WithPlaceholders(c.Placeholders))
+	}
+	if c.OutOfOrder {
+		options = append(options, going.WithOutOfOrder())
+	}
+	if c.CommitEachMigration {
+		options = append(options, going.WithCommitEachMigration())
+	}
+	return options
+}
+
+// New returns a going.G of MS and DS for db.
+func (c *Config) New(db *sql.DB, options ...going.Option) (*going.G, error) {
+	ds, err := c.DS(db)
+	if err != nil {
+		return nil, err
+	}
+	return going.New(c.MS(), ds, append(c.Options(), options...)...)
+}
+
+func splitList(s string) []string {
+	var res []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+func withSearchPath(dsn, schema string) (string, error) {
+	if strings.Contains(dsn, "search_path") {
+		return dsn, nil
+	}
+	if strings.Contains(dsn, "://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", err
+		}
+		q := u.Query()
+		q.Set("search_path", schema)
+		u.RawQuery = q.Encode()
+		return u.String(), nil
+	}
+	return strings.TrimSpace(dsn + " search_path=" + schema), nil
+}
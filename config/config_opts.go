@@ -0,0 +1,45 @@
+package config
+
+import "os"
+
+type Option func(o *opts)
+
+type opts struct {
+	profile string
+	environ []string
+}
+
+func newOpts(options []Option) *opts {
+	o := &opts{environ: os.Environ()}
+	for _, option := range options {
+		option(o)
+	}
+	if o.profile == "" {
+		o.profile = lookup(o.environ, EnvProfile)
+	}
+	return o
+}
+
+// WithProfile selects a profile instead of the one named by GOING_PROFILE.
+func WithProfile(name string) Option {
+	return func(o *opts) {
+		o.profile = name
+	}
+}
+
+// WithEnviron replaces os.Environ as the environment, as key=value pairs.
+func WithEnviron(environ []string) Option {
+	return func(o *opts) {
+		o.environ = environ
+	}
+}
+
+func lookup(environ []string, key string) string {
+	var v string
+	for _, kv := range environ {
+		if len(kv) > len(key) && kv[:len(key)] == key && kv[len(key)] == '=' {
+			v = kv[len(key)+1:]
+		}
+	}
+	return v
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mlu1109/going/datasrc/postgres"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const yamlConfig = `
+dsn: postgres://localhost/app
+locations: [migrations, shared]
+schema: app
+createSchema: true
+placeholders:
+  owner: app
+  region: eu
+checksum: sha256
+profiles:
+  ci:
+    dsn: postgres://ci/app
+    placeholders:
+      owner: ci
+    outOfOrder: true
+`
+
+const tomlConfig = `
+dsn = "postgres://localhost/app"
+locations = ["migrations", "shared"]
+schema = "app"
+createSchema = true
+checksum = "sha256"
+
+[placeholders]
+owner = "app"
+region = "eu"
+
+[profiles.ci]
+dsn = "postgres://ci/app"
+outOfOrder = true
+
+[profiles.ci.placeholders]
+owner = "ci"
+`
+
+func TestLoad_whenProfile_thenOverrideFileValues(t *testing.T) {
+	for fn, content := range map[string]string{"going.yaml": yamlConfig, "going.toml": tomlConfig} {
+		dir := t.TempDir()
+		path := filepath.Join(dir, fn)
+		err := os.WriteFile(path, []byte(content), 0644)
+		assert.Nil(t, err)
+		c, err := Load(path, WithProfile("ci"), WithEnviron(nil))
+		assert.Nil(t, err, fn)
+		assert.Equal(t, &Config{
+			DSN:          "postgres://ci/app",
+			Locations:    []string{filepath.Join(dir, "migrations"), filepath.Join(dir, "shared")},
+			Schema:       "app",
+			CreateSchema: true,
+			Table:        postgres.DefaultHistoryTableName,
+			Placeholders: map[string]string{"owner": "ci", "region": "eu"},
+			Checksum:     "sha256",
+			OutOfOrder:   true,
+		}, c, fn)
+	}
+}
+
+func TestLoad_whenEnvironment_thenOverrideFileAndProfile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "going.yml")
+	err := os.WriteFile(path, []byte(yamlConfig), 0644)
+	assert.Nil(t, err)
+	c, err := Load(path, WithEnviron([]string{
+		"GOING_PROFILE=ci",
+		"GOING_DSN=postgres://env/app",
+		"GOING_DIR=a,b",
+		"GOING_OUT_OF_ORDER=false",
+		"GOING_PLACEHOLDERS_region=us",
+		"GOING_TABLE=",
+	}))
+	assert.Nil(t, err)
+	assert.Equal(t, "postgres://env/app", c.DSN)
+	assert.Equal(t, []string{"a", "b"}, c.Locations)
+	assert.False(t, c.OutOfOrder)
+	assert.Equal(t, map[string]string{"owner": "ci", "region": "us"}, c.Placeholders)
+	assert.Equal(t, postgres.DefaultHistoryTableName, c.Table)
+}
+
+func TestLoad_whenInvalid_thenReturnError(t *testing.T) {
+	dir := t.TempDir()
+	for fn, content := range map[string]string{
+		"unknown_field.yaml":   "dns: postgres://localhost/app",
+		"unknown_field.toml":   `dns = "postgres://localhost/app"`,
+		"unknown_checksum.yml": "checksum: sha1",
+		"going.json":           "{}",
+	} {
+		path := filepath.Join(dir, fn)
+		err := os.WriteFile(path, []byte(content), 0644)
+		assert.Nil(t, err)
+		_, err = Load(path, WithEnviron(nil))
+		assert.NotNil(t, err, fn)
+	}
+	for fn, content := range map[string]string{
+		"unknown_profile_field.yaml": "profiles: {prod: {shcema: app}}",
+		"unknown_profile_field.toml": "[profiles.prod]\nshcema = \"app\"",
+	} {
+		path := filepath.Join(dir, fn)
+		err := os.WriteFile(path, []byte(content), 0644)
+		assert.Nil(t, err)
+		_, err = Load(path, WithProfile("prod"), WithEnviron(nil))
+		if assert.NotNil(t, err, fn) {
+			assert.Contains(t, err.Error(), "shcema", fn)
+		}
+	}
+	path := filepath.Join(dir, "going.yaml")
+	err := os.WriteFile(path, []byte(yamlConfig), 0644)
+	assert.Nil(t, err)
+	_, err = Load(path, WithProfile("prod"), WithEnviron(nil))
+	assert.ErrorIs(t, err, ErrUnknownProfile)
+}
+
+func TestWithSearchPath(t *testing.T) {
+	for dsn, expected := range map[string]string{
+		"postgres://u@localhost/db?sslmode=disable": "postgres://u@localhost/db?search_path=s&sslmode=disable",
+		"host=localhost dbname=db":                  "host=localhost dbname=db search_path=s",
+		"host=localhost search_path=other":          "host=localhost search_path=other",
+	} {
+		res, err := withSearchPath(dsn, "s")
+		assert.Nil(t, err)
+		assert.Equal(t, expected, res)
+	}
+}
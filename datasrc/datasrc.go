@@ -1,10 +1,32 @@
 package datasrc
 
+import (
+	"context"
+	"database/sql"
+
+	"github.com/mlu1109/going/version"
+)
+
+// DS is a datasource that migrations are applied to. All methods but
+// GetAppliedMigrations require the lock to be held. GetAppliedMigrations
+// returns records in the order they were applied.
 type DS interface {
-	ApplyMigration(version uint, description string, checksum string, content string) error
-	GetAppliedMigrations() ([]*Migration, error)
-	Clean() error
-	Init() error
-	Lock() error
+	ApplyMigration(ctx context.Context, m *Migration, content string) error
+	// ApplyFuncMigration runs fn in the transaction that records m.
+	ApplyFuncMigration(ctx context.Context, m *Migration, fn func(ctx context.Context, tx *sql.Tx) error) error
+	// ApplyMigrationWithoutTx commits the work done so far and executes content
+	// outside of a transaction. m is recorded as failed if content fails.
+	ApplyMigrationWithoutTx(ctx context.Context, m *Migration, content string) error
+	UndoMigration(ctx context.Context, v version.Version, content string) error
+	ApplyBaseline(ctx context.Context, v version.Version, description string) error
+	UpdateMigration(ctx context.Context, v version.Version, description string, checksum string) error
+	GetAppliedMigrations(ctx context.Context) ([]*Migration, error)
+	RemoveFailedMigrations(ctx context.Context) error
+	Clean(ctx context.Context) error
+	Init(ctx context.Context) error
+	Lock(ctx context.Context) error
 	Unlock(commit bool) error
+	// Commit commits the work done so far without releasing the lock.
+	Commit(ctx context.Context) error
+	Tx() (*sql.Tx, error)
 }
@@ -0,0 +1,330 @@
+// Package sqlds implements the history table, lock and transaction handling
+// shared by the database/sql datasources. A datasource embeds DS and adds its
+// dialect specific table setup and Clean.
+package sqlds
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mlu1109/going/datasrc"
+	"github.com/mlu1109/going/version"
+)
+
+// Queries are the history table statements of a dialect. %s is replaced by the
+// name of the history table.
+type Queries struct {
+	// InsertMigration takes version, description, checksum, kind,
+	// execution_time_ms, success, out_of_order and installed_by.
+	InsertMigration string
+	// InsertBaseline takes version, description and installed_by.
+	InsertBaseline string
+	// UpdateMigration takes description, checksum and version.
+	UpdateMigration string
+	// DeleteMigration takes version.
+	DeleteMigration  string
+	DeleteFailed     string
+	SelectMigrations string
+}
+
+// Locker locks the history table against other processes on the connection
+// that holds the transaction.
+type Locker interface {
+	Acquire(ctx context.Context, conn *sql.Conn) error
+	Release(conn *sql.Conn) error
+}
+
+type Config struct {
+	DB               *sql.DB
+	HistoryTableName string
+	Queries          Queries
+	// Locker may be nil if beginning a transaction locks out other processes.
+	Locker Locker
+	// InstalledBy is recorded as installed_by, NULL if empty.
+	InstalledBy string
+	// SplitStatements, if set, splits the content of migrations run outside
+	// of a transaction into statements executed one at a time.
+	SplitStatements func(content string) []string
+}
+
+type DS struct {
+	lock *sync.Mutex
+
+	conn *sql.Conn
+	tx   *sql.Tx
+
+	config Config
+}
+
+func New(config Config) *DS {
+	return &DS{
+		lock:   &sync.Mutex{},
+		config: config,
+	}
+}
+
+// ApplyMigration executes content and records m, without a version if m is repeatable.
+func (d *DS) ApplyMigration(ctx context.Context, m *datasrc.Migration, content string) error {
+	tx, err := d.getTX()
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	_, err = tx.ExecContext(ctx, content)
+	if err != nil {
+		return err
+	}
+	return d.execInsertMigration(ctx, tx, m, time.Since(start), true)
+}
+
+func (d *DS) ApplyFuncMigration(ctx context.Context, m *datasrc.Migration, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	tx, err := d.getTX()
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	err = fn(ctx, tx)
+	if err != nil {
+		return err
+	}
+	return d.execInsertMigration(ctx, tx, m, time.Since(start), true)
+}
+
+// ApplyMigrationWithoutTx commits the transaction, executes content outside of
+// a transaction and records m, then begins a new transaction. If content fails
+// m is recorded as failed, even if ctx was cancelled, since the statements
+// executed so far can not be rolled back.
+func (d *DS) ApplyMigrationWithoutTx(ctx context.Context, m *datasrc.Migration, content string) error {
+	return d.commitAndBegin(ctx, func() error {
+		statements := []string{content}
+		if d.config.SplitStatements != nil {
+			statements = d.config.SplitStatements(content)
+		}
+		start := time.Now()
+		for _, statement := range statements {
+			_, execErr := d.conn.ExecContext(ctx, statement)
+			if execErr != nil {
+				err := d.execInsertMigration(context.Background(), d.conn, m, time.Since(start), false)
+				if err != nil {
+					return errors.Join(execErr, fmt.Errorf("failed to record failed migration: %w", err))
+				}
+				return execErr
+			}
+		}
+		return d.execInsertMigration(ctx, d.conn, m, time.Since(start), true)
+	})
+}
+
+// RemoveFailedMigrations deletes the records of failed migrations.
+func (d *DS) RemoveFailedMigrations(ctx context.Context) error {
+	tx, err := d.getTX()
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(d.config.Queries.DeleteFailed, d.config.HistoryTableName))
+	return err
+}
+
+func (d *DS) UndoMigration(ctx context.Context, v version.Version, content string) error {
+	tx, err := d.getTX()
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, content)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		fmt.Sprintf(d.config.Queries.DeleteMigration, d.config.HistoryTableName),
+		string(v))
+	return err
+}
+
+func (d *DS) ApplyBaseline(ctx context.Context, v version.Version, description string) error {
+	tx, err := d.getTX()
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		fmt.Sprintf(d.config.Queries.InsertBaseline, d.config.HistoryTableName),
+		string(v), description, d.nullableInstalledBy())
+	return err
+}
+
+func (d *DS) UpdateMigration(ctx context.Context, v version.Version, description string, checksum string) error {
+	tx, err := d.getTX()
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		fmt.Sprintf(d.config.Queries.UpdateMigration, d.config.HistoryTableName),
+		description, checksum, string(v))
+	return err
+}
+
+// GetAppliedMigrations reads through the transaction if the lock is held and
+// directly from the database otherwise.
+func (d *DS) GetAppliedMigrations(ctx context.Context) ([]*datasrc.Migration, error) {
+	var rows *sql.Rows
+	var err error
+	if tx, txErr := d.getTX(); txErr == nil {
+		rows, err = tx.QueryContext(ctx, fmt.Sprintf(d.config.Queries.SelectMigrations, d.config.HistoryTableName))
+	} else {
+		rows, err = d.config.DB.QueryContext(ctx, fmt.Sprintf(d.config.Queries.SelectMigrations, d.config.HistoryTableName))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return datasrc.ScanMigrations(rows)
+}
+
+// Lock acquires the lock on a dedicated connection and begins a transaction
+// on it.
+func (d *DS) Lock(ctx context.Context) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.tx != nil {
+		return fmt.Errorf("already locked")
+	}
+	conn, err := d.config.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	if d.config.Locker != nil {
+		err = d.config.Locker.Acquire(ctx, conn)
+		if err != nil {
+			conn.Close()
+			return err
+		}
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		d.release(conn)
+		return err
+	}
+	d.conn = conn
+	d.tx = tx
+	return nil
+}
+
+func (d *DS) Unlock(commit bool) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.tx == nil {
+		return fmt.Errorf("not locked")
+	}
+	tx, conn := d.tx, d.conn
+	d.tx, d.conn = nil, nil
+	var err error
+	if commit {
+		err = tx.Commit()
+	} else {
+		err = tx.Rollback()
+	}
+	releaseErr := d.release(conn)
+	if err != nil {
+		return err
+	}
+	return releaseErr
+}
+
+// Setup runs init, e.g. the creation of the history table, under the lock and
+// commits if it succeeds.
+func (d *DS) Setup(ctx context.Context, init func(ctx context.Context) error) (err error) {
+	err = d.Lock(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to lock history table: %w", err)
+	}
+	defer func() {
+		unlockErr := d.Unlock(err == nil)
+		if err == nil && unlockErr != nil {
+			err = fmt.Errorf("failed to unlock history table: %w", unlockErr)
+		}
+	}()
+	err = init(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to set up history table: %w", err)
+	}
+	return nil
+}
+
+// Commit commits the transaction and begins a new one while keeping the lock.
+func (d *DS) Commit(ctx context.Context) error {
+	return d.commitAndBegin(ctx, nil)
+}
+
+// commitAndBegin commits the transaction, runs fn, if any, outside of a
+// transaction on the locked connection and begins a new transaction.
+func (d *DS) commitAndBegin(ctx context.Context, fn func() error) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.tx == nil {
+		return fmt.Errorf("not locked")
+	}
+	err := d.tx.Commit()
+	var fnErr error
+	if err == nil {
+		if fn != nil {
+			fnErr = fn()
+		}
+		d.tx, err = d.conn.BeginTx(ctx, nil)
+	}
+	if err != nil {
+		// Release the lock since there is no transaction left to unlock
+		d.release(d.conn)
+		d.tx, d.conn = nil, nil
+		if fnErr != nil {
+			return errors.Join(fnErr, err)
+		}
+		return err
+	}
+	return fnErr
+}
+
+// Tx returns the transaction held while the lock is acquired.
+func (d *DS) Tx() (*sql.Tx, error) {
+	return d.getTX()
+}
+
+// getTX reads the transaction under the mutex since the history may be read
+// while another goroutine holds the lock.
+func (d *DS) getTX() (*sql.Tx, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.tx == nil {
+		return nil, fmt.Errorf("Lock not acquired")
+	}
+	return d.tx, nil
+}
+
+// release releases the lock held by conn and closes it.
+func (d *DS) release(conn *sql.Conn) error {
+	defer conn.Close()
+	if d.config.Locker == nil {
+		return nil
+	}
+	return d.config.Locker.Release(conn)
+}
+
+func (d *DS) execInsertMigration(ctx context.Context, e execer, m *datasrc.Migration, executionTime time.Duration, success bool) error {
+	_, err := e.ExecContext(ctx,
+		fmt.Sprintf(d.config.Queries.InsertMigration, d.config.HistoryTableName),
+		toNullableVersion(m), m.Description, m.Checksum, m.Kind, executionTime.Milliseconds(), success, m.OutOfOrder, d.nullableInstalledBy())
+	return err
+}
+
+func (d *DS) nullableInstalledBy() sql.NullString {
+	return sql.NullString{String: d.config.InstalledBy, Valid: d.config.InstalledBy != ""}
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func toNullableVersion(m *datasrc.Migration) sql.NullString {
+	return sql.NullString{String: string(m.Version), Valid: m.Kind != datasrc.KindRepeatable}
+}
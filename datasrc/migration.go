@@ -1,15 +1,69 @@
 package datasrc
 
+import (
+	"database/sql"
+	"time"
+
+	"github.com/mlu1109/going/version"
+)
+
+type Kind string
+
+const (
+	KindVersioned  Kind = "versioned"
+	KindBaseline   Kind = "baseline"
+	KindRepeatable Kind = "repeatable"
+)
+
+// Migration is a history record. Repeatable migrations have no version and
+// a record for every time they were applied.
 type Migration struct {
-	Version     uint
+	Version     version.Version
 	Description string
 	Checksum    string
+	Kind        Kind
+	// OutOfOrder is set if a migration with a higher version was applied first.
+	OutOfOrder bool
+	// The fields below are set by the datasource when the record is written.
+	// Records written before they were introduced have zero values.
+	InstalledRank int
+	InstalledOn   time.Time
+	InstalledBy   string
+	ExecutionTime time.Duration
+	Success       bool
 }
 
-func NewMigration(version uint, description string, checksum string) *Migration {
+func NewMigration(v version.Version, description string, checksum string) *Migration {
 	return &Migration{
-		Version:     version,
+		Version:     v,
 		Description: description,
 		Checksum:    checksum,
+		Kind:        KindVersioned,
+	}
+}
+
+// ScanMigrations reads records selected as installed_rank, version,
+// description, checksum, kind, installed_on, installed_by, execution_time_ms,
+// success and out_of_order.
+func ScanMigrations(rows *sql.Rows) ([]*Migration, error) {
+	defer rows.Close()
+	var res []*Migration
+	for rows.Next() {
+		m := &Migration{}
+		var v sql.NullString
+		var executionTime sql.NullInt64
+		var installedOn sql.NullTime
+		var installedBy sql.NullString
+		err := rows.Scan(&m.InstalledRank, &v, &m.Description, &m.Checksum, &m.Kind,
+			&installedOn, &installedBy, &executionTime, &m.Success, &m.OutOfOrder)
+		if err != nil {
+			return nil, err
+		}
+		m.Version = version.Version(v.String)
+		m.InstalledOn = installedOn.Time
+		m.InstalledBy = installedBy.String
+		m.ExecutionTime = time.Duration(executionTime.Int64) * time.Millisecond
+		res = append(res, m)
 	}
+	return res, rows.Err()
 }
@@ -0,0 +1,188 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mlu1109/going/datasrc"
+	"github.com/mlu1109/going/datasrc/internal/sqlds"
+	"github.com/mlu1109/going/logger"
+)
+
+// DS is a MySQL or MariaDB datasource. Since DDL commits implicitly, the lock
+// is a named lock held by a dedicated connection rather than the transaction,
+// and a versioned migration failing partway can not be rolled back. Versioned
+// migrations are therefore applied like ApplyMigrationWithoutTx and recorded
+// as failed, for Validate to report and Repair to remove, once the already
+// applied statements have been reverted by hand.
+// The database must be opened with multiStatements=true for migrations with
+// more than one statement and parseTime=true to read the history.
+type DS struct {
+	*sqlds.DS
+
+	historyTableName string
+	lockTimeout      time.Duration
+
+	db *sql.DB
+
+	logger logger.Logger
+}
+
+const (
+	DefaultHistoryTableName = "going_schema_history"
+	DefaultLockTimeout      = time.Minute
+
+	queryCreateHistoryTable = `create table if not exists %s (
+		installed_rank	integer auto_increment primary key,
+		version 	varchar(255) null unique,
+		description	text,
+		checksum 	varchar(255),
+		kind 		varchar(16) not null default 'versioned',
+		installed_on	timestamp not null default current_timestamp,
+		installed_by	varchar(255),
+		execution_time_ms	integer not null default 0,
+		success 	boolean not null default true,
+		out_of_order	boolean not null default false
+	);`
+	queryGetLock         = "select get_lock(concat(database(), ':', ?), ?);"
+	queryReleaseLock     = "select release_lock(concat(database(), ':', ?));"
+	querySelectTables    = "select table_name, table_type from information_schema.tables where table_schema = database() order by table_type = 'BASE TABLE';"
+	queryDropTable       = "drop table if exists `%s`;"
+	queryDropView        = "drop view if exists `%s`;"
+	queryDisableFKChecks = "set foreign_key_checks = 0;"
+	queryEnableFKChecks  = "set foreign_key_checks = 1;"
+)
+
+var queries = sqlds.Queries{
+	InsertMigration: "insert into %s (version, description, checksum, kind, execution_time_ms, success, out_of_order, installed_by) values (?, ?, ?, ?, ?, ?, ?, coalesce(?, current_user()));",
+	InsertBaseline:  "insert into %s (version, description, checksum, kind, installed_by) values (?, ?, '', 'baseline', coalesce(?, current_user()));",
+	UpdateMigration: "update %s set description = ?, checksum = ? where version = ?;",
+	DeleteMigration: "delete from %s where version = ?;",
+	DeleteFailed:    "delete from %s where not success;",
+	SelectMigrations: `select installed_rank, version, description, checksum, kind,
+		installed_on, installed_by, execution_time_ms, success, out_of_order from %s order by installed_rank;`,
+}
+
+var ErrLockTimeout = errors.New("timed out waiting for lock")
+
+// New returns a DS after creating the history table.
+func New(options ...Option) (*DS, error) {
+	dsmysql := &DS{
+		historyTableName: DefaultHistoryTableName,
+		lockTimeout:      DefaultLockTimeout,
+		logger:           logger.Std(nil),
+	}
+	for _, option := range options {
+		option(dsmysql)
+	}
+	dsmysql.DS = sqlds.New(sqlds.Config{
+		DB:               dsmysql.db,
+		HistoryTableName: dsmysql.historyTableName,
+		Queries:          queries,
+		Locker:           &namedLock{name: dsmysql.historyTableName, timeout: dsmysql.lockTimeout},
+	})
+	err := dsmysql.Setup(context.Background(), dsmysql.Init)
+	if err != nil {
+		return nil, err
+	}
+	return dsmysql, nil
+}
+
+// ApplyMigration records a failed versioned migration, see DS.
+func (d *DS) ApplyMigration(ctx context.Context, m *datasrc.Migration, content string) error {
+	if m.Kind != datasrc.KindVersioned {
+		return d.DS.ApplyMigration(ctx, m, content)
+	}
+	return d.ApplyMigrationWithoutTx(ctx, m, content)
+}
+
+// Clean drops all tables and views of the current database, including the
+// history table, and recreates the history table.
+func (d *DS) Clean(ctx context.Context) error {
+	tx, err := d.Tx()
+	if err != nil {
+		return err
+	}
+	rows, err := tx.QueryContext(ctx, querySelectTables)
+	if err != nil {
+		return err
+	}
+	var drops []string
+	for rows.Next() {
+		var name, typ string
+		err = rows.Scan(&name, &typ)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		if typ == "VIEW" {
+			drops = append(drops, fmt.Sprintf(queryDropView, name))
+		} else {
+			drops = append(drops, fmt.Sprintf(queryDropTable, name))
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	d.logger.Info("Dropping tables and views...", "count", len(drops))
+	_, err = tx.ExecContext(ctx, queryDisableFKChecks)
+	if err != nil {
+		return err
+	}
+	for _, drop := range drops {
+		_, err = tx.ExecContext(ctx, drop)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = tx.ExecContext(ctx, queryEnableFKChecks)
+	if err != nil {
+		return err
+	}
+	d.logger.Info("Creating history table...", "table", d.historyTableName)
+	return d.execCreateTable(ctx)
+}
+
+func (d *DS) Init(ctx context.Context) error {
+	_, err := d.Tx()
+	if err != nil {
+		return err
+	}
+	return d.execCreateTable(ctx)
+}
+
+type namedLock struct {
+	name    string
+	timeout time.Duration
+}
+
+// Acquire takes a named lock, waiting at most the lock timeout.
+func (l *namedLock) Acquire(ctx context.Context, conn *sql.Conn) error {
+	var acquired sql.NullInt64
+	err := conn.QueryRowContext(ctx, queryGetLock, l.name, int(l.timeout.Seconds())).Scan(&acquired)
+	if err != nil {
+		return err
+	}
+	if !acquired.Valid || acquired.Int64 != 1 {
+		return fmt.Errorf("%w: %s", ErrLockTimeout, l.name)
+	}
+	return nil
+}
+
+func (l *namedLock) Release(conn *sql.Conn) error {
+	_, err := conn.ExecContext(context.Background(), queryReleaseLock, l.name)
+	return err
+}
+
+func (d *DS) execCreateTable(ctx context.Context) error {
+	tx, err := d.Tx()
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(queryCreateHistoryTable, d.historyTableName))
+	return err
+}
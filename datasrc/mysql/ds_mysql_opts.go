@@ -0,0 +1,38 @@
+package mysql
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/mlu1109/going/logger"
+)
+
+type Option func(d *DS)
+
+func WithDB(db *sql.DB) Option {
+	return func(d *DS) {
+		d.db = db
+	}
+}
+
+// WithHistoryTable sets the name of the history table, going_schema_history by
+// default. It also names the lock.
+func WithHistoryTable(name string) Option {
+	return func(d *DS) {
+		d.historyTableName = name
+	}
+}
+
+// WithLockTimeout sets how long Lock waits for another process to release the
+// lock. It is rounded down to whole seconds.
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(d *DS) {
+		d.lockTimeout = timeout
+	}
+}
+
+func WithLogger(l logger.Logger) Option {
+	return func(d *DS) {
+		d.logger = l
+	}
+}
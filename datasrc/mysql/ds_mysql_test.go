@@ -0,0 +1,150 @@
+package mysql_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/mlu1109/going"
+	"github.com/mlu1109/going/datasrc/mysql"
+	"github.com/mlu1109/going/logger"
+	"github.com/mlu1109/going/migrsrc"
+	"github.com/mlu1109/going/migrsrc/slice"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+// Started by going_test/docker-compose.yaml
+const dsn = "going_user:going_password@tcp(localhost:3306)/going_test?multiStatements=true&parseTime=true"
+
+var migrations = []*migrsrc.Migration{
+	{
+		Version:     "1",
+		Description: "Migration V1",
+		Content: `
+		create table test_table (
+			id  varchar(255) primary key,
+			num integer
+		);`,
+	},
+	{
+		Version:     "2",
+		Description: "Migration V2",
+		Content: `
+		alter table test_table add column v2_added integer;
+		create view test_view as select id from test_table;`,
+	},
+}
+
+func newTestGoing(t *testing.T, migrations []*migrsrc.Migration) (*sql.DB, *mysql.DS, *going.G) {
+	db, err := sql.Open("mysql", dsn)
+	assert.Nil(t, err)
+	t.Cleanup(func() { db.Close() })
+	if err = db.Ping(); err != nil {
+		t.Skipf("mysql not available: %s", err)
+	}
+	ds, err := mysql.New(mysql.WithDB(db), mysql.WithLockTimeout(time.Second), mysql.WithLogger(logger.Nop()))
+	assert.Nil(t, err)
+	g, err := going.New(slice.New(migrations), ds, going.WithLogger(logger.Nop()))
+	assert.Nil(t, err)
+	err = g.Clean()
+	assert.Nil(t, err)
+	return db, ds, g
+}
+
+func TestMigrate(t *testing.T) {
+
+	t.Run("Apply migrations once", func(t *testing.T) {
+		// Given
+		db, ds, g := newTestGoing(t, migrations)
+		// When
+		err := g.Migrate()
+		assert.Nil(t, err)
+		err = g.Migrate()
+		// Then ...
+		assert.Nil(t, err)
+		applied, err := ds.GetAppliedMigrations(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(applied))
+		_, err = db.Exec("insert into test_table (id, num, v2_added) values ('1', 1, 2)")
+		assert.Nil(t, err)
+	})
+
+	t.Run("Record migration failing after implicit commit", func(t *testing.T) {
+		// Given
+		failing := &migrsrc.Migration{
+			Version:     "3",
+			Description: "Migration V3",
+			Content: `
+			alter table test_table add column v3_added integer;
+			alter table missing_table add column v3_added integer;`,
+		}
+		db, ds, g := newTestGoing(t, append(migrations[:2:2], failing))
+		// When
+		err := g.Migrate()
+		// Then ...
+		assert.NotNil(t, err)
+		// ... the first statement is committed
+		_, err = db.Exec("select v3_added from test_table")
+		assert.Nil(t, err)
+		// ... and the migration is recorded as failed
+		applied, err := ds.GetAppliedMigrations(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, 3, len(applied))
+		assert.False(t, applied[2].Success)
+		err = g.Validate()
+		var failed *going.FailedMigrationError
+		assert.ErrorAs(t, err, &failed)
+	})
+}
+
+func TestLock(t *testing.T) {
+
+	t.Run("Time out while another process holds the lock", func(t *testing.T) {
+		// Given
+		db, ds, _ := newTestGoing(t, migrations)
+		other, err := mysql.New(mysql.WithDB(db), mysql.WithLockTimeout(time.Second), mysql.WithLogger(logger.Nop()))
+		assert.Nil(t, err)
+		err = other.Lock(context.Background())
+		assert.Nil(t, err)
+		defer other.Unlock(false)
+		// When
+		err = ds.Lock(context.Background())
+		// Then ...
+		assert.ErrorIs(t, err, mysql.ErrLockTimeout)
+	})
+
+	t.Run("Fail to create while another process holds the lock", func(t *testing.T) {
+		// Given
+		db, ds, _ := newTestGoing(t, migrations)
+		err := ds.Lock(context.Background())
+		assert.Nil(t, err)
+		defer ds.Unlock(false)
+		// When
+		waiting, err := mysql.New(mysql.WithDB(db), mysql.WithLockTimeout(0), mysql.WithLogger(logger.Nop()))
+		// Then ...
+		assert.ErrorIs(t, err, mysql.ErrLockTimeout)
+		assert.Nil(t, waiting)
+	})
+}
+
+func TestClean(t *testing.T) {
+
+	t.Run("Drop all tables and views", func(t *testing.T) {
+		// Given
+		db, ds, g := newTestGoing(t, migrations)
+		err := g.Migrate()
+		assert.Nil(t, err)
+		// When
+		err = g.Clean()
+		// Then ...
+		assert.Nil(t, err)
+		_, err = db.Exec("select * from test_table")
+		assert.NotNil(t, err)
+		applied, err := ds.GetAppliedMigrations(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(applied))
+	})
+}
@@ -1,187 +1,261 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
-	"sync"
+	"time"
 
-	"github.com/mlu1109/going/datasrc"
+	"github.com/mlu1109/going/datasrc/internal/sqlds"
+	"github.com/mlu1109/going/logger"
 )
 
 type DS struct {
-	lock *sync.Mutex
+	*sqlds.DS
 
 	historyTableName string
 	schemaName       string
 	createSchema     bool
+	lockTimeout      time.Duration
 
 	db *sql.DB
-	tx *sql.Tx
+
+	logger logger.Logger
 }
 
 const (
 	DefaultHistoryTableName = "going_schema_history"
 	DefaultSchema           = "public"
+	DefaultLockTimeout      = time.Minute
+
+	lockPollInterval = 250 * time.Millisecond
 
 	queryCreateSchema       = "create schema if not exists %s;"
 	queryCreateHistoryTable = `create table if not exists %s (
-		version 	integer primary key,
+		installed_rank	serial primary key,
+		version 	text,
 		description	text,
-		checksum 	text
+		checksum 	text,
+		kind 		text not null default 'versioned',
+		installed_on	timestamp with time zone not null default now(),
+		installed_by	text not null default current_user,
+		execution_time_ms	integer not null default 0,
+		success 	boolean not null default true,
+		out_of_order	boolean not null default false
 	);`
-	queryInsertMigration  = "insert into %s (version, description, checksum) values ($1, $2, $3);"
-	querySelectMigrations = "select version, description, checksum from %s;"
-	queryDropSchema       = "drop schema if exists %s cascade;"
+	queryHasColumn        = "select count(*) > 0 from pg_attribute where attrelid = to_regclass($1) and attname = $2 and not attisdropped;"
+	queryColumnType       = "select format_type(atttypid, atttypmod) from pg_attribute where attrelid = to_regclass($1) and attname = $2 and not attisdropped;"
+	queryAlterVersionType = "alter table %s alter column version type text;"
+	queryAddKindColumn    = "alter table %s add column if not exists kind text not null default 'versioned';"
+	queryAddInstalledRank = `alter table %[1]s drop constraint if exists %[1]s_pkey;
+		alter table %[1]s add column installed_rank serial primary key;
+		alter table %[1]s alter column version drop not null;`
+	queryAddAuditColumns = `alter table %[1]s add column if not exists installed_on timestamp with time zone;
+		alter table %[1]s alter column installed_on set default now();
+		alter table %[1]s add column if not exists installed_by text;
+		alter table %[1]s alter column installed_by set default current_user;
+		alter table %[1]s add column if not exists execution_time_ms integer;
+		alter table %[1]s alter column execution_time_ms set default 0;
+		alter table %[1]s add column if not exists success boolean not null default true;
+		alter table %[1]s add column if not exists out_of_order boolean not null default false;`
+	queryCreateVersionIndex = "create unique index if not exists %[1]s_version_idx on %[1]s (version) where kind <> 'repeatable';"
+	queryDropSchema         = "drop schema if exists %s cascade;"
+	queryTryAdvisoryLock    = "select pg_try_advisory_lock(hashtext($1));"
+	queryAdvisoryUnlock     = "select pg_advisory_unlock(hashtext($1));"
 )
 
+var queries = sqlds.Queries{
+	InsertMigration: "insert into %s (version, description, checksum, kind, execution_time_ms, success, out_of_order, installed_by) values ($1, $2, $3, $4, $5, $6, $7, coalesce($8::text, current_user));",
+	InsertBaseline:  "insert into %s (version, description, checksum, kind, installed_by) values ($1, $2, '', 'baseline', coalesce($3::text, current_user));",
+	UpdateMigration: "update %s set description = $1, checksum = $2 where version = $3;",
+	DeleteMigration: "delete from %s where version = $1;",
+	DeleteFailed:    "delete from %s where not success;",
+	SelectMigrations: `select installed_rank, version, description, checksum, kind,
+		installed_on, installed_by, execution_time_ms, success, out_of_order from %s order by installed_rank;`,
+}
+
+var ErrLockTimeout = errors.New("timed out waiting for lock")
+
+// New returns the result of Open and panics if it fails.
 func New(options ...Option) *DS {
+	dspg, err := Open(options...)
+	if err != nil {
+		log.Panic(err)
+	}
+	return dspg
+}
+
+// Open returns a DS after creating or upgrading the history table under the
+// lock, waiting for at most the lock timeout if another process holds it.
+func Open(options ...Option) (*DS, error) {
 	dspg := &DS{
-		lock:             &sync.Mutex{},
 		schemaName:       DefaultSchema,
 		historyTableName: DefaultHistoryTableName,
 		createSchema:     false,
+		lockTimeout:      DefaultLockTimeout,
+		logger:           logger.Std(nil),
 	}
 	for _, option := range options {
 		option(dspg)
 	}
-	err := dspg.Lock()
+	dspg.DS = sqlds.New(sqlds.Config{
+		DB:               dspg.db,
+		HistoryTableName: dspg.historyTableName,
+		Queries:          queries,
+		Locker: &advisoryLock{
+			key:     dspg.schemaName + "." + dspg.historyTableName,
+			timeout: dspg.lockTimeout,
+			logger:  dspg.logger,
+		},
+		// A query of several statements runs in an implicit transaction
+		SplitStatements: splitStatements,
+	})
+	err := dspg.Setup(context.Background(), dspg.Init)
 	if err != nil {
-		log.Panic(err)
+		return nil, err
 	}
-	defer dspg.Unlock(err == nil)
-	if dspg.createSchema {
-		err = dspg.execCreateSchema()
+	return dspg, nil
+}
+
+func (d *DS) Clean(ctx context.Context) error {
+	if !d.createSchema {
+		return fmt.Errorf("can not clean unmanaged schema")
 	}
+	tx, err := d.Tx()
 	if err != nil {
-		log.Panic(err)
+		return err
 	}
-	dspg.execCreateTable()
+	d.logger.Info("Dropping managed schema...", "schema", d.schemaName)
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(queryDropSchema, d.schemaName))
 	if err != nil {
-		log.Panic(err)
+		return err
 	}
-	return dspg
-}
-
-func (d *DS) ApplyMigration(version uint, description string, checksum string, content string) error {
-	tx, err := d.getTX()
+	d.logger.Info("Creating managed schema...", "schema", d.schemaName)
+	err = d.execCreateSchema(ctx)
 	if err != nil {
 		return err
 	}
-	_, err = tx.Exec(content)
+	d.logger.Info("Creating history table...", "table", d.historyTableName)
+	err = d.execCreateTable(ctx)
 	if err != nil {
 		return err
 	}
-	_, err = tx.Exec(
-		fmt.Sprintf(queryInsertMigration, d.historyTableName),
-		version, description, checksum)
-	return err
+	return d.execUpgradeTable(ctx)
 }
 
-func (d *DS) GetAppliedMigrations() ([]*datasrc.Migration, error) {
-	tx, err := d.getTX()
+func (d *DS) Init(ctx context.Context) error {
+	_, err := d.Tx()
 	if err != nil {
-		return nil, err
+		return err
 	}
-	rows, err := tx.Query(fmt.Sprintf(querySelectMigrations, d.historyTableName))
+	if d.createSchema {
+		err = d.execCreateSchema(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	err = d.execCreateTable(ctx)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
-	var res []*datasrc.Migration
-	for rows.Next() {
-		m := &datasrc.Migration{}
-		err := rows.Scan(&m.Version, &m.Description, &m.Checksum)
+	return d.execUpgradeTable(ctx)
+}
+
+type advisoryLock struct {
+	key     string
+	timeout time.Duration
+	logger  logger.Logger
+}
+
+// Acquire takes a session level advisory lock keyed by the schema and history
+// table. Concurrent processes migrating the same history table wait for each
+// other for at most the lock timeout.
+func (l *advisoryLock) Acquire(ctx context.Context, conn *sql.Conn) error {
+	deadline := time.Now().Add(l.timeout)
+	for {
+		var acquired bool
+		err := conn.QueryRowContext(ctx, queryTryAdvisoryLock, l.key).Scan(&acquired)
 		if err != nil {
-			return nil, err
+			return err
+		}
+		if acquired {
+			return nil
+		}
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("%w: %s", ErrLockTimeout, l.key)
+		}
+		l.logger.Debug("Waiting for lock...", "key", l.key)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(lockPollInterval):
 		}
-		res = append(res, m)
 	}
-	return res, nil
 }
 
-func (d *DS) Clean() error {
-	if !d.createSchema {
-		return fmt.Errorf("can not clean unmanaged schema")
+func (l *advisoryLock) Release(conn *sql.Conn) error {
+	_, err := conn.ExecContext(context.Background(), queryAdvisoryUnlock, l.key)
+	return err
+}
+
+func (d *DS) execCreateSchema(ctx context.Context) error {
+	tx, err := d.Tx()
+	if err != nil {
+		return err
 	}
-	tx, err := d.getTX()
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(queryCreateSchema, d.schemaName))
+	return err
+}
+
+func (d *DS) execCreateTable(ctx context.Context) error {
+	tx, err := d.Tx()
 	if err != nil {
 		return err
 	}
-	log.Print("Dropping managed schema...")
-	_, err = tx.Exec(fmt.Sprintf(queryDropSchema, d.schemaName))
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(queryCreateHistoryTable, d.historyTableName))
+	return err
+}
+
+// execUpgradeTable brings a history table created by an earlier version up to date.
+func (d *DS) execUpgradeTable(ctx context.Context) error {
+	tx, err := d.Tx()
 	if err != nil {
 		return err
 	}
-	log.Print("Creating managed schema...")
-	err = d.execCreateSchema()
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(queryAddKindColumn, d.historyTableName))
 	if err != nil {
 		return err
 	}
-	log.Print("Creating history table...")
-	err = d.execCreateTable()
-	return err
-}
-
-func (d *DS) Init() error {
-	tx, err := d.getTX()
+	var hasInstalledRank bool
+	err = tx.QueryRowContext(ctx, queryHasColumn, d.historyTableName, "installed_rank").Scan(&hasInstalledRank)
 	if err != nil {
 		return err
 	}
-	if d.createSchema {
-		_, err = tx.Exec(queryCreateSchema, d.schemaName)
+	if !hasInstalledRank {
+		d.logger.Info("Upgrading history table...", "table", d.historyTableName, "column", "installed_rank")
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(queryAddInstalledRank, d.historyTableName))
 		if err != nil {
 			return err
 		}
 	}
-	_, err = tx.Exec(queryCreateHistoryTable, d.historyTableName)
-	return err
-}
-
-func (d *DS) Lock() error {
-	d.lock.Lock()
-	defer d.lock.Unlock()
-	if d.tx == nil {
-		tx, err := d.db.Begin()
+	var versionType string
+	err = tx.QueryRowContext(ctx, queryColumnType, d.historyTableName, "version").Scan(&versionType)
+	if err != nil {
+		return err
+	}
+	if versionType != "text" {
+		d.logger.Info("Upgrading history table...", "table", d.historyTableName, "column", "version")
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(queryAlterVersionType, d.historyTableName))
 		if err != nil {
 			return err
 		}
-		d.tx = tx
-		return nil
-	} else {
-		return fmt.Errorf("already locked")
-	}
-}
-
-func (d *DS) Unlock(commit bool) error {
-	d.lock.Lock()
-	defer d.lock.Unlock()
-	if d.tx != nil {
-		tx := d.tx
-		d.tx = nil
-		if commit {
-			return tx.Commit()
-		} else {
-			return tx.Rollback()
-		}
-	} else {
-		return fmt.Errorf("not locked")
 	}
-}
-
-func (d *DS) getTX() (*sql.Tx, error) {
-	if d.tx == nil {
-		return nil, fmt.Errorf("Lock not acquired")
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(queryAddAuditColumns, d.historyTableName))
+	if err != nil {
+		return err
 	}
-	return d.tx, nil
-}
-
-func (d *DS) execCreateSchema() error {
-	_, err := d.tx.Exec(fmt.Sprintf(queryCreateSchema, d.schemaName))
-	return err
-}
-
-func (d *DS) execCreateTable() error {
-	_, err := d.tx.Exec(fmt.Sprintf(queryCreateHistoryTable, d.historyTableName))
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(queryCreateVersionIndex, d.historyTableName))
 	return err
 }
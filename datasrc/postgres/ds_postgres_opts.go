@@ -1,6 +1,11 @@
 package postgres
 
-import "database/sql"
+import (
+	"database/sql"
+	"time"
+
+	"github.com/mlu1109/going/logger"
+)
 
 type Option func(d *DS)
 
@@ -11,8 +16,27 @@ func WithSchema(schema_name string, create ...bool) Option {
 	}
 }
 
+func WithHistoryTable(name string) Option {
+	return func(d *DS) {
+		d.historyTableName = name
+	}
+}
+
 func WithDB(db *sql.DB) Option {
 	return func(d *DS) {
 		d.db = db
 	}
 }
+
+// WithLockTimeout sets how long Lock waits for another process holding the lock.
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(d *DS) {
+		d.lockTimeout = timeout
+	}
+}
+
+func WithLogger(l logger.Logger) Option {
+	return func(d *DS) {
+		d.logger = l
+	}
+}
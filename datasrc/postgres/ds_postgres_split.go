@@ -0,0 +1,114 @@
+package postgres
+
+import "strings"
+
+// splitStatements splits content at semicolons outside of quotes, dollar
+// quotes and comments. Parts without a statement, e.g. a trailing comment,
+// are dropped.
+func splitStatements(content string) []string {
+	var statements []string
+	start := 0
+	hasStatement := false
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == '-' && strings.HasPrefix(content[i:], "--"):
+			if end := strings.IndexByte(content[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(content)
+			}
+		case c == '/' && strings.HasPrefix(content[i:], "/*"):
+			i = skipBlockComment(content, i)
+		case c == '\'' || c == '"':
+			hasStatement = true
+			i = skipQuoted(content, i, isEscapeString(content, i))
+		case c == '$':
+			hasStatement = true
+			if tag := dollarQuoteTag(content[i:]); tag != "" {
+				if end := strings.Index(content[i+len(tag):], tag); end >= 0 {
+					i += len(tag) + end + len(tag) - 1
+				} else {
+					i = len(content)
+				}
+			}
+		case c == ';':
+			if hasStatement {
+				statements = append(statements, strings.TrimSpace(content[start:i+1]))
+			}
+			start = i + 1
+			hasStatement = false
+		case c != ' ' && c != '\t' && c != '\n' && c != '\r':
+			hasStatement = true
+		}
+	}
+	if hasStatement {
+		statements = append(statements, strings.TrimSpace(content[start:]))
+	}
+	return statements
+}
+
+// skipBlockComment returns the index of the end of the, possibly nested,
+// block comment starting at i.
+func skipBlockComment(content string, i int) int {
+	depth := 0
+	for ; i < len(content)-1; i++ {
+		switch content[i : i+2] {
+		case "/*":
+			depth++
+			i++
+		case "*/":
+			depth--
+			i++
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(content)
+}
+
+// skipQuoted returns the index of the quote ending the string or identifier
+// starting at i. A doubled quote is part of the string.
+func skipQuoted(content string, i int, backslashEscapes bool) int {
+	quote := content[i]
+	for i++; i < len(content); i++ {
+		switch {
+		case backslashEscapes && content[i] == '\\':
+			i++
+		case content[i] == quote:
+			if i+1 < len(content) && content[i+1] == quote {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return len(content)
+}
+
+// isEscapeString reports if the quote at i starts an E'...' string.
+func isEscapeString(content string, i int) bool {
+	if content[i] != '\'' || i == 0 || (content[i-1] != 'E' && content[i-1] != 'e') {
+		return false
+	}
+	return i == 1 || !isIdentifierChar(content[i-2])
+}
+
+// dollarQuoteTag returns the $tag$ that s starts with, if any.
+func dollarQuoteTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '$':
+			return s[:i+1]
+		case isIdentifierChar(s[i]) && !(i == 1 && s[i] >= '0' && s[i] <= '9'):
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+func isIdentifierChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
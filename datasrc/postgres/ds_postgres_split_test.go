@@ -0,0 +1,29 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitStatements(t *testing.T) {
+	for content, expected := range map[string][]string{
+		"-- going:no-transaction\ncreate index concurrently a on t (a);\ncreate index concurrently b on t (b);\n": {
+			"-- going:no-transaction\ncreate index concurrently a on t (a);",
+			"create index concurrently b on t (b);",
+		},
+		"vacuum a; vacuum b": {"vacuum a;", "vacuum b"},
+		"insert into t values ('a;''b', \"c;\"\"d\", E'e\\';f'); select 1;": {
+			"insert into t values ('a;''b', \"c;\"\"d\", E'e\\';f');",
+			"select 1;",
+		},
+		"create function f() returns int as $body$ select 1; $body$ language sql; select $1;": {
+			"create function f() returns int as $body$ select 1; $body$ language sql;",
+			"select $1;",
+		},
+		"select 1; /* a; /* nested; */ b; */ -- c;\n": {"select 1;"},
+		";; \n": nil,
+	} {
+		assert.Equal(t, expected, splitStatements(content), content)
+	}
+}
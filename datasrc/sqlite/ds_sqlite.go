@@ -0,0 +1,147 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os/user"
+
+	"github.com/mlu1109/going/datasrc/internal/sqlds"
+	"github.com/mlu1109/going/logger"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type DS struct {
+	*sqlds.DS
+
+	historyTableName string
+
+	db *sql.DB
+	// err is set by an option that failed to open the database
+	err error
+
+	logger logger.Logger
+}
+
+const (
+	DefaultHistoryTableName = "going_schema_history"
+
+	queryCreateHistoryTable = `create table if not exists %[1]s (
+		installed_rank	integer primary key autoincrement,
+		version 	text,
+		description	text,
+		checksum 	text,
+		kind 		text not null default 'versioned',
+		installed_on	timestamp not null default current_timestamp,
+		installed_by	text,
+		execution_time_ms	integer not null default 0,
+		success 	boolean not null default true,
+		out_of_order	boolean not null default false
+	);
+	create unique index if not exists %[1]s_version_idx on %[1]s (version) where kind <> 'repeatable';`
+	querySelectObjects = "select type, name from sqlite_master where type in ('table', 'view') and name not like 'sqlite_%' order by type = 'table';"
+	queryDropObject    = "drop %s if exists \"%s\";"
+)
+
+var queries = sqlds.Queries{
+	InsertMigration: "insert into %s (version, description, checksum, kind, execution_time_ms, success, out_of_order, installed_by) values (?, ?, ?, ?, ?, ?, ?, ?);",
+	InsertBaseline:  "insert into %s (version, description, checksum, kind, installed_by) values (?, ?, '', 'baseline', ?);",
+	UpdateMigration: "update %s set description = ?, checksum = ? where version = ?;",
+	DeleteMigration: "delete from %s where version = ?;",
+	DeleteFailed:    "delete from %s where not success;",
+	SelectMigrations: `select installed_rank, version, description, checksum, kind,
+		installed_on, installed_by, execution_time_ms, success, out_of_order from %s order by installed_rank;`,
+}
+
+// New returns a DS after creating the history table.
+func New(options ...Option) (*DS, error) {
+	dssqlite := &DS{
+		historyTableName: DefaultHistoryTableName,
+		logger:           logger.Std(nil),
+	}
+	for _, option := range options {
+		option(dssqlite)
+	}
+	if dssqlite.err != nil {
+		return nil, dssqlite.err
+	}
+	if dssqlite.db == nil {
+		return nil, errors.New("sqlite: no database, use WithDB, WithFile or WithMemory")
+	}
+	// Beginning a transaction locks the database, so there is no Locker
+	dssqlite.DS = sqlds.New(sqlds.Config{
+		DB:               dssqlite.db,
+		HistoryTableName: dssqlite.historyTableName,
+		Queries:          queries,
+		InstalledBy:      currentUser(),
+	})
+	err := dssqlite.Setup(context.Background(), dssqlite.Init)
+	if err != nil {
+		return nil, err
+	}
+	return dssqlite, nil
+}
+
+// Clean drops all views and tables, including the history table, and
+// recreates the history table.
+func (d *DS) Clean(ctx context.Context) error {
+	tx, err := d.Tx()
+	if err != nil {
+		return err
+	}
+	rows, err := tx.QueryContext(ctx, querySelectObjects)
+	if err != nil {
+		return err
+	}
+	var drops []string
+	for rows.Next() {
+		var typ, name string
+		err = rows.Scan(&typ, &name)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		drops = append(drops, fmt.Sprintf(queryDropObject, typ, name))
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	d.logger.Info("Dropping tables and views...", "count", len(drops))
+	for _, drop := range drops {
+		_, err = tx.ExecContext(ctx, drop)
+		if err != nil {
+			return err
+		}
+	}
+	d.logger.Info("Creating history table...", "table", d.historyTableName)
+	return d.execCreateTable(ctx)
+}
+
+func (d *DS) Init(ctx context.Context) error {
+	_, err := d.Tx()
+	if err != nil {
+		return err
+	}
+	return d.execCreateTable(ctx)
+}
+
+func (d *DS) execCreateTable(ctx context.Context) error {
+	tx, err := d.Tx()
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(queryCreateHistoryTable, d.historyTableName))
+	return err
+}
+
+// currentUser is recorded as installed_by since SQLite has no users.
+func currentUser() string {
+	u, err := user.Current()
+	if err != nil {
+		return ""
+	}
+	return u.Username
+}
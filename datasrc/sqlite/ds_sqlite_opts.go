@@ -0,0 +1,51 @@
+package sqlite
+
+import (
+	"database/sql"
+
+	"github.com/mlu1109/going/logger"
+)
+
+type Option func(d *DS)
+
+func WithDB(db *sql.DB) Option {
+	return func(d *DS) {
+		d.db = db
+	}
+}
+
+// WithHistoryTable sets the name of the history table, going_schema_history by
+// default.
+func WithHistoryTable(name string) Option {
+	return func(d *DS) {
+		d.historyTableName = name
+	}
+}
+
+// WithFile opens the database file at path, creating it if needed. Lock takes
+// a write lock on the file.
+func WithFile(path string) Option {
+	return func(d *DS) {
+		d.db, d.err = sql.Open("sqlite3", "file:"+path+"?_txlock=immediate")
+	}
+}
+
+// WithMemory opens a private in-memory database. It is limited to a single
+// connection since every connection would otherwise get its own database.
+func WithMemory() Option {
+	return func(d *DS) {
+		db, err := sql.Open("sqlite3", ":memory:")
+		if err != nil {
+			d.err = err
+			return
+		}
+		db.SetMaxOpenConns(1)
+		d.db = db
+	}
+}
+
+func WithLogger(l logger.Logger) Option {
+	return func(d *DS) {
+		d.logger = l
+	}
+}
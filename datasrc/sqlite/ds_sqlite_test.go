@@ -0,0 +1,382 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mlu1109/going"
+	"github.com/mlu1109/going/datasrc"
+	"github.com/mlu1109/going/datasrc/sqlite"
+	"github.com/mlu1109/going/logger"
+	"github.com/mlu1109/going/migrsrc"
+	"github.com/mlu1109/going/migrsrc/slice"
+	"github.com/mlu1109/going/version"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var migrations = []*migrsrc.Migration{
+	{
+		Version:     "1",
+		Description: "Migration V1",
+		Content: `
+		create table test_table (
+			id  varchar(255) primary key,
+			num integer
+		);`,
+		UndoContent: `
+		drop table test_table;`,
+	},
+	{
+		Version:     "2",
+		Description: "Migration V2",
+		Content: `
+		alter table test_table add column v2_added integer;
+		create view test_view as select id from test_table;`,
+	},
+}
+
+func newTestDS(t *testing.T) (*sql.DB, *sqlite.DS) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "going.db"))
+	assert.Nil(t, err)
+	t.Cleanup(func() { db.Close() })
+	ds, err := sqlite.New(sqlite.WithDB(db), sqlite.WithLogger(logger.Nop()))
+	assert.Nil(t, err)
+	return db, ds
+}
+
+func getAppliedMigrations(ds *sqlite.DS) ([]*datasrc.Migration, error) {
+	return ds.GetAppliedMigrations(context.Background())
+}
+
+func TestMigrate(t *testing.T) {
+
+	t.Run("Apply migrations once", func(t *testing.T) {
+		// Given
+		db, ds := newTestDS(t)
+		g, err := going.New(slice.New(migrations), ds, going.WithLogger(logger.Nop()))
+		assert.Nil(t, err)
+		// When
+		err = g.Migrate()
+		assert.Nil(t, err)
+		err = g.Migrate()
+		// Then ...
+		assert.Nil(t, err)
+		applied, err := getAppliedMigrations(ds)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(applied))
+		assert.Equal(t, version.Version("2"), applied[1].Version)
+		_, err = db.Exec("insert into test_table (id, num, v2_added) values ('1', 1, 2)")
+		assert.Nil(t, err)
+		// ... with an audit trail
+		assert.Equal(t, 2, applied[1].InstalledRank)
+		assert.WithinDuration(t, time.Now(), applied[1].InstalledOn, time.Minute)
+		assert.NotEmpty(t, applied[1].InstalledBy)
+		assert.True(t, applied[1].Success)
+	})
+
+	t.Run("Roll back failing migration", func(t *testing.T) {
+		// Given
+		db, ds := newTestDS(t)
+		failing := append(migrations[:1:1], migrsrc.NewMigration("2", "Failing", "create table test_table2 (id int); select * from missing;"))
+		g, err := going.New(slice.New(failing), ds, going.WithLogger(logger.Nop()))
+		assert.Nil(t, err)
+		// When
+		err = g.Migrate()
+		// Then ...
+		assert.NotNil(t, err)
+		// ... nothing was applied
+		applied, err := getAppliedMigrations(ds)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(applied))
+		_, err = db.Exec("select * from test_table")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Apply repeatable migration without version", func(t *testing.T) {
+		// Given
+		_, ds := newTestDS(t)
+		view := migrsrc.NewRepeatableMigration("test_view2", "create view if not exists test_view2 as select num from test_table;")
+		g, err := going.New(slice.New(append(migrations[:1:1], view)), ds, going.WithLogger(logger.Nop()))
+		assert.Nil(t, err)
+		// When
+		err = g.Migrate()
+		// Then ...
+		assert.Nil(t, err)
+		applied, err := getAppliedMigrations(ds)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(applied))
+		assert.Equal(t, datasrc.KindRepeatable, applied[1].Kind)
+	})
+}
+
+func TestUndo(t *testing.T) {
+
+	t.Run("Undo latest migration", func(t *testing.T) {
+		// Given
+		db, ds := newTestDS(t)
+		g, err := going.New(slice.New(migrations[:1]), ds, going.WithLogger(logger.Nop()))
+		assert.Nil(t, err)
+		err = g.Migrate()
+		assert.Nil(t, err)
+		// When
+		err = g.Undo(1)
+		// Then ...
+		assert.Nil(t, err)
+		applied, err := getAppliedMigrations(ds)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(applied))
+		_, err = db.Exec("select * from test_table")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Log undo in dry run", func(t *testing.T) {
+		// Given
+		db, ds := newTestDS(t)
+		g, err := going.New(slice.New(migrations[:1]), ds, going.WithLogger(logger.Nop()))
+		assert.Nil(t, err)
+		err = g.Migrate()
+		assert.Nil(t, err)
+		g, err = going.New(slice.New(migrations[:1]), ds, going.WithLogger(logger.Nop()), going.WithDryRun())
+		assert.Nil(t, err)
+		// When
+		err = g.Undo(1)
+		// Then ...
+		assert.Nil(t, err)
+		// ... the migration is still applied
+		applied, err := getAppliedMigrations(ds)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(applied))
+		_, err = db.Exec("select * from test_table")
+		assert.Nil(t, err)
+	})
+}
+
+func TestClean(t *testing.T) {
+
+	t.Run("Drop all tables and views", func(t *testing.T) {
+		// Given
+		db, ds := newTestDS(t)
+		g, err := going.New(slice.New(migrations), ds, going.WithLogger(logger.Nop()))
+		assert.Nil(t, err)
+		err = g.Migrate()
+		assert.Nil(t, err)
+		// When
+		err = g.Clean()
+		// Then ...
+		assert.Nil(t, err)
+		_, err = db.Exec("select * from test_view")
+		assert.NotNil(t, err)
+		_, err = db.Exec("select * from test_table")
+		assert.NotNil(t, err)
+		// ... history table was recreated empty
+		applied, err := getAppliedMigrations(ds)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(applied))
+	})
+}
+
+func TestNew(t *testing.T) {
+
+	t.Run("Create history table with another name", func(t *testing.T) {
+		// Given
+		db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "going.db"))
+		assert.Nil(t, err)
+		defer db.Close()
+		ds, err := sqlite.New(sqlite.WithDB(db), sqlite.WithHistoryTable("history"), sqlite.WithLogger(logger.Nop()))
+		assert.Nil(t, err)
+		g, err := going.New(slice.New(migrations), ds, going.WithLogger(logger.Nop()))
+		assert.Nil(t, err)
+		// When
+		err = g.Migrate()
+		// Then ...
+		assert.Nil(t, err)
+		// ... migrations were recorded in the named table
+		var count int
+		err = db.QueryRow("select count(*) from history").Scan(&count)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, count)
+		_, err = db.Exec("select * from going_schema_history")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Fail without database", func(t *testing.T) {
+		// When
+		ds, err := sqlite.New(sqlite.WithLogger(logger.Nop()))
+		// Then
+		assert.NotNil(t, err)
+		assert.Nil(t, ds)
+	})
+}
+
+func TestMemory(t *testing.T) {
+
+	t.Run("Apply migrations to in-memory database", func(t *testing.T) {
+		// Given
+		ds, err := sqlite.New(sqlite.WithMemory(), sqlite.WithLogger(logger.Nop()))
+		assert.Nil(t, err)
+		g, err := going.New(slice.New(migrations), ds, going.WithLogger(logger.Nop()))
+		assert.Nil(t, err)
+		// When
+		err = g.Migrate()
+		// Then ...
+		assert.Nil(t, err)
+		applied, err := getAppliedMigrations(ds)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(applied))
+	})
+}
+
+func TestTransactionModes(t *testing.T) {
+
+	t.Run("Run migration outside of the transaction", func(t *testing.T) {
+		// Given
+		_, ds := newTestDS(t)
+		vacuum := &migrsrc.Migration{Version: "3", Description: "Vacuum", Content: "vacuum;", NoTransaction: true}
+		g, err := going.New(slice.New(append(migrations[:2:2], vacuum)), ds, going.WithLogger(logger.Nop()))
+		assert.Nil(t, err)
+		// When
+		err = g.Migrate()
+		// Then ...
+		assert.Nil(t, err)
+		applied, err := getAppliedMigrations(ds)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, len(applied))
+		assert.True(t, applied[2].Success)
+	})
+
+	t.Run("Record failed migration outside of the transaction", func(t *testing.T) {
+		// Given
+		_, ds := newTestDS(t)
+		failing := &migrsrc.Migration{Version: "3", Description: "Failing", Content: "select * from missing;", NoTransaction: true}
+		g, err := going.New(slice.New(append(migrations[:2:2], failing)), ds, going.WithLogger(logger.Nop()))
+		assert.Nil(t, err)
+		// When
+		err = g.Migrate()
+		// Then ...
+		assert.NotNil(t, err)
+		// ... previous migrations were committed and the failure was recorded
+		applied, err := getAppliedMigrations(ds)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, len(applied))
+		assert.False(t, applied[2].Success)
+		err = g.Validate()
+		var failedErr *going.FailedMigrationError
+		assert.ErrorAs(t, err, &failedErr)
+		// When
+		failing.Content = "select 1;"
+		err = g.Repair()
+		assert.Nil(t, err)
+		err = g.Migrate()
+		// Then ...
+		assert.Nil(t, err)
+		applied, err = getAppliedMigrations(ds)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, len(applied))
+		assert.True(t, applied[2].Success)
+	})
+
+	t.Run("Commit after each migration", func(t *testing.T) {
+		// Given
+		_, ds := newTestDS(t)
+		failing := migrsrc.NewMigration("3", "Failing", "select * from missing;")
+		g, err := going.New(slice.New(append(migrations[:2:2], failing)), ds,
+			going.WithLogger(logger.Nop()), going.WithCommitEachMigration())
+		assert.Nil(t, err)
+		// When
+		err = g.Migrate()
+		// Then ...
+		assert.NotNil(t, err)
+		// ... only the failing migration was rolled back
+		applied, err := getAppliedMigrations(ds)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(applied))
+	})
+}
+
+func TestOutOfOrder(t *testing.T) {
+
+	t.Run("Apply lower version after a higher one", func(t *testing.T) {
+		// Given
+		_, ds := newTestDS(t)
+		v3 := migrsrc.NewMigration("3", "Migration V3", "create table test_table3 (id int);")
+		g, err := going.New(slice.New([]*migrsrc.Migration{migrations[0], v3}), ds, going.WithLogger(logger.Nop()))
+		assert.Nil(t, err)
+		err = g.Migrate()
+		assert.Nil(t, err)
+		merged := slice.New([]*migrsrc.Migration{migrations[0], migrations[1], v3})
+		// When
+		g, err = going.New(merged, ds, going.WithLogger(logger.Nop()))
+		assert.Nil(t, err)
+		err = g.Migrate()
+		// Then ...
+		var outOfOrderErr *going.OutOfOrderError
+		assert.ErrorAs(t, err, &outOfOrderErr)
+		// When
+		g, err = going.New(merged, ds, going.WithLogger(logger.Nop()), going.WithOutOfOrder())
+		assert.Nil(t, err)
+		err = g.Migrate()
+		// Then ...
+		assert.Nil(t, err)
+		applied, err := getAppliedMigrations(ds)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, len(applied))
+		assert.Equal(t, version.Version("2"), applied[2].Version)
+		assert.True(t, applied[2].OutOfOrder)
+		assert.False(t, applied[1].OutOfOrder)
+	})
+}
+
+func TestVersions(t *testing.T) {
+
+	t.Run("Apply dotted and timestamp versions in numeric order", func(t *testing.T) {
+		// Given
+		_, ds := newTestDS(t)
+		g, err := going.New(slice.New([]*migrsrc.Migration{
+			migrsrc.NewMigration("20261018143000", "Timestamp", "select 1;"),
+			migrsrc.NewMigration("1.10", "Dotted V1.10", "select 1;"),
+			migrsrc.NewMigration("1.2", "Dotted V1.2", "select 1;"),
+		}), ds, going.WithLogger(logger.Nop()))
+		assert.Nil(t, err)
+		// When
+		err = g.Migrate()
+		// Then ...
+		assert.Nil(t, err)
+		applied, err := getAppliedMigrations(ds)
+		assert.Nil(t, err)
+		assert.Equal(t, []version.Version{"1.2", "1.10", "20261018143000"},
+			[]version.Version{applied[0].Version, applied[1].Version, applied[2].Version})
+		// ... and validates on the next run
+		err = g.Validate()
+		assert.Nil(t, err)
+	})
+}
+
+func TestConcurrentRead(t *testing.T) {
+
+	t.Run("Read history while migrating", func(t *testing.T) {
+		// Given
+		_, ds := newTestDS(t)
+		g, err := going.New(slice.New(migrations), ds, going.WithLogger(logger.Nop()), going.WithCommitEachMigration())
+		assert.Nil(t, err)
+		done := make(chan error)
+		// When
+		go func() {
+			done <- g.Migrate()
+		}()
+		for migrating := true; migrating; {
+			select {
+			case err = <-done:
+				migrating = false
+			default:
+				// Reads may fail while the transaction is replaced
+				ds.GetAppliedMigrations(context.Background())
+			}
+		}
+		// Then
+		assert.Nil(t, err)
+	})
+}
@@ -0,0 +1,103 @@
+package going
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mlu1109/going/migrsrc"
+	"github.com/mlu1109/going/version"
+)
+
+type ChecksumMismatchError struct {
+	Version         version.Version
+	LocalChecksum   string
+	AppliedChecksum string
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("local checksum does not match applied checksum: %s != %s", e.LocalChecksum, e.AppliedChecksum)
+}
+
+type DescriptionMismatchError struct {
+	Version            version.Version
+	LocalDescription   string
+	AppliedDescription string
+}
+
+func (e *DescriptionMismatchError) Error() string {
+	return "local description does not match applied description"
+}
+
+type MissingLocalMigrationError struct {
+	Version version.Version
+}
+
+func (e *MissingLocalMigrationError) Error() string {
+	return fmt.Sprintf("applied migration has no local migration: %s", e.Version)
+}
+
+// OutOfOrderError is returned for a local unapplied migration with a lower
+// version than an already applied migration.
+type OutOfOrderError struct {
+	Version        version.Version
+	AppliedVersion version.Version
+}
+
+func (e *OutOfOrderError) Error() string {
+	return fmt.Sprintf("encountered a local unapplied migration with a lower version than an already applied migration: %s vs %s", e.Version, e.AppliedVersion)
+}
+
+// FailedMigrationError is returned when a migration that ran outside of a
+// transaction failed. Repair removes the failed record.
+type FailedMigrationError struct {
+	Version version.Version
+}
+
+func (e *FailedMigrationError) Error() string {
+	return fmt.Sprintf("migration failed and must be repaired: %s", e.Version)
+}
+
+// ValidationError holds every problem found by Validate.
+type ValidationError struct {
+	Errors []error
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("found %d validation errors: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+func (e *ValidationError) Unwrap() []error {
+	return e.Errors
+}
+
+// MigrationError is returned when applying a single migration fails.
+type MigrationError struct {
+	Migration *migrsrc.Migration
+	Err       error
+}
+
+func (e *MigrationError) Error() string {
+	return fmt.Sprintf("failed to apply migration %s", e.Err)
+}
+
+func (e *MigrationError) Unwrap() error {
+	return e.Err
+}
+
+// UnresolvedPlaceholderError is returned when a migration references a
+// placeholder that was not given to WithPlaceholders.
+type UnresolvedPlaceholderError struct {
+	Placeholder string
+	Migration   *migrsrc.Migration
+}
+
+func (e *UnresolvedPlaceholderError) Error() string {
+	if e.Migration.Source != "" {
+		return fmt.Sprintf("unresolved placeholder ${%s} in %s", e.Placeholder, e.Migration.Source)
+	}
+	return fmt.Sprintf("unresolved placeholder ${%s} in migration %s", e.Placeholder, e.Migration)
+}
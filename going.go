@@ -1,13 +1,15 @@
 package going
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log"
-	"sort"
+	"time"
 
 	"github.com/mlu1109/going/datasrc"
+	"github.com/mlu1109/going/logger"
 	"github.com/mlu1109/going/migrsrc"
+	"github.com/mlu1109/going/version"
 )
 
 type G struct {
@@ -15,15 +17,27 @@ type G struct {
 	ds datasrc.DS
 
 	checksum Checksum
+	target   *version.Version
+	dryRun   bool
+	logger   logger.Logger
+	hooks    hooks
+
+	placeholders map[string]string
+	commitEach   bool
+	outOfOrder   bool
 }
 
 var ErrInitiaization = errors.New("failed to initialize going")
+var ErrUndo = errors.New("failed to undo migrations")
+var ErrBaseline = errors.New("failed to baseline datasource")
+var ErrDryRun = errors.New("not supported in dry run")
 
 func New(ms migrsrc.MS, ds datasrc.DS, opts ...Option) (*G, error) {
 	g := &G{
 		ms:       ms,
 		ds:       ds,
-		checksum: DefaultChecksumFn}
+		checksum: DefaultChecksumFn,
+		logger:   logger.Std(nil)}
 	for _, opt := range opts {
 		opt(g)
 	}
@@ -33,94 +47,706 @@ func New(ms migrsrc.MS, ds datasrc.DS, opts ...Option) (*G, error) {
 	if g.ms == nil {
 		return nil, fmt.Errorf("%w: migration source is nil", ErrInitiaization)
 	}
+	if g.target != nil {
+		target, err := version.Parse(string(*g.target))
+		if err != nil {
+			return nil, fmt.Errorf("%w: target: %v", ErrInitiaization, err)
+		}
+		g.target = &target
+	}
 	return g, nil
 }
 
 func (g *G) Migrate() error {
-	log.Print("Migrating datasource...")
+	return g.MigrateContext(context.Background())
+}
+
+func (g *G) MigrateContext(ctx context.Context) (err error) {
+	if g.dryRun {
+		return g.logPlan(ctx)
+	}
+	start := time.Now()
+	g.logger.Info("Migrating datasource...")
+	defer g.afterMigrate(ctx, &err)
 	// Load local migrations and map them by version
-	local, err := g.ms.Load()
+	localMappedByVersion, localRepeatables, err := g.loadLocal()
+	if err != nil {
+		return err
+	}
+	// Acquire datasource lock
+	err = g.ds.Lock(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to lock datasource: %w", err)
+	}
+	defer g.unlock(&err)
+	err = g.runCallbacks(ctx, g.hooks.beforeMigrate, nil)
+	if err != nil {
+		return fmt.Errorf("before migrate callback failed: %w", err)
+	}
+	// Load applied migrations and map them by version
+	appliedMappedByVersion, appliedRepeatables, err := g.loadApplied(ctx)
+	if err != nil {
+		return err
+	}
+	// Validate migrations and get pending migrations
+	pending, err := g.getPendingMigrations(localMappedByVersion, localRepeatables, appliedMappedByVersion, appliedRepeatables)
+	if err != nil {
+		return err
+	}
+	// Apply migrations
+	err = g.applyMigrations(ctx, pending, getLatestAppliedVersion(appliedMappedByVersion))
+	if err != nil {
+		return err
+	}
+	g.logger.Info("Datasource was successfully migrated!", "duration", time.Since(start))
+	return nil
+}
+
+// MigrateTo applies or undoes migrations until target is the latest applied version.
+func (g *G) MigrateTo(target version.Version) error {
+	return g.MigrateToContext(context.Background(), target)
+}
+
+func (g *G) MigrateToContext(ctx context.Context, target version.Version) (err error) {
+	target, err = version.Parse(string(target))
+	if err != nil {
+		return err
+	}
+	if g.dryRun {
+		return g.logMigrateToPlan(ctx, target)
+	}
+	start := time.Now()
+	g.logger.Info("Migrating datasource to target version...", "target", target)
+	defer g.afterMigrate(ctx, &err)
+	// Load local migrations and map them by version
+	localMappedByVersion, _, err := g.loadLocal()
+	if err != nil {
+		return err
+	}
+	// Acquire datasource lock
+	err = g.ds.Lock(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to load migrations: %w", err)
+		return fmt.Errorf("failed to lock datasource: %w", err)
 	}
-	localMappedByVersion, err := getLocalMigrationsMappedByVersion(local)
+	defer g.unlock(&err)
+	err = g.runCallbacks(ctx, g.hooks.beforeMigrate, nil)
+	if err != nil {
+		return fmt.Errorf("before migrate callback failed: %w", err)
+	}
+	// Load applied migrations and map them by version
+	appliedMappedByVersion, _, err := g.loadApplied(ctx)
+	if err != nil {
+		return err
+	}
+	// Validate migrations and get the versions to undo or the migrations to apply
+	undoVersions, pending, err := g.getMigrateToSteps(localMappedByVersion, appliedMappedByVersion, target)
+	if err != nil {
+		return err
+	}
+	// Undo applied migrations above target, if any, otherwise apply migrations up to target
+	if len(undoVersions) > 0 {
+		err = g.undoVersions(ctx, localMappedByVersion, appliedMappedByVersion, undoVersions)
+	} else {
+		err = g.applyMigrations(ctx, pending, getLatestAppliedVersion(appliedMappedByVersion))
+	}
+	if err != nil {
+		return err
+	}
+	g.logger.Info("Datasource was successfully migrated to target version!", "target", target, "duration", time.Since(start))
+	return nil
+}
+
+// Undo reverts the n latest applied migrations using their undo scripts.
+func (g *G) Undo(n int) error {
+	return g.UndoContext(context.Background(), n)
+}
+
+func (g *G) UndoContext(ctx context.Context, n int) (err error) {
+	if n < 0 {
+		return fmt.Errorf("%w: can not undo a negative number of migrations", ErrUndo)
+	}
+	if g.dryRun {
+		return g.logUndoPlan(ctx, n)
+	}
+	start := time.Now()
+	g.logger.Info("Undoing migrations...", "count", n)
+	// Load local migrations and map them by version
+	localMappedByVersion, _, err := g.loadLocal()
 	if err != nil {
 		return err
 	}
 	// Acquire datasource lock
-	err = g.ds.Lock()
+	err = g.ds.Lock(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to lock datasource: %w", err)
 	}
-	defer g.ds.Unlock(err == nil)
+	defer g.unlock(&err)
 	// Load applied migrations and map them by version
-	applied, err := g.ds.GetAppliedMigrations()
+	appliedMappedByVersion, _, err := g.loadApplied(ctx)
 	if err != nil {
 		return err
 	}
-	appliedMappedByVersion, err := getDatasrcMigrationMappedByVersion(applied)
+	// Validate migrations
+	_, err = g.getApplicableVersions(localMappedByVersion, appliedMappedByVersion)
 	if err != nil {
 		return err
 	}
-	// Validate migrations and get applicable versions
-	applicableVersions, err := g.getApplicableVersions(localMappedByVersion, appliedMappedByVersion)
+	// Undo the latest applied migrations
+	undoVersions, err := getLatestAppliedVersions(appliedMappedByVersion, n)
 	if err != nil {
 		return err
 	}
-	// Apply migrations
-	log.Printf("Applying %d migrations...", len(applicableVersions))
-	for i, v := range applicableVersions {
-		log.Printf("Applying migration %d/%d: %d...", i+1, len(applicableVersions), v)
-		applied, err := g.apply(localMappedByVersion[v])
+	err = g.undoVersions(ctx, localMappedByVersion, appliedMappedByVersion, undoVersions)
+	if err != nil {
+		return err
+	}
+	g.logger.Info("Migrations were successfully undone!", "count", n, "duration", time.Since(start))
+	return nil
+}
+
+// Plan returns the migrations Migrate would apply without applying them.
+func (g *G) Plan() (*Plan, error) {
+	return g.PlanContext(context.Background())
+}
+
+func (g *G) PlanContext(ctx context.Context) (*Plan, error) {
+	// Load local migrations and map them by version
+	localMappedByVersion, localRepeatables, err := g.loadLocal()
+	if err != nil {
+		return nil, err
+	}
+	// Load applied migrations and map them by version without locking
+	appliedMappedByVersion, appliedRepeatables, err := g.loadApplied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Validate migrations and get pending migrations
+	pending, err := g.getPendingMigrations(localMappedByVersion, localRepeatables, appliedMappedByVersion, appliedRepeatables)
+	if err != nil {
+		return nil, err
+	}
+	return g.getPlan(pending)
+}
+
+// Info returns the state of every local and applied migration ordered by version.
+func (g *G) Info() ([]*MigrationInfo, error) {
+	return g.InfoContext(context.Background())
+}
+
+func (g *G) InfoContext(ctx context.Context) ([]*MigrationInfo, error) {
+	// Load local migrations and map them by version
+	localMappedByVersion, localRepeatables, err := g.loadLocal()
+	if err != nil {
+		return nil, err
+	}
+	// Load applied migrations and map them by version without locking
+	appliedMappedByVersion, appliedRepeatables, err := g.loadApplied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	baselineVersion, hasBaseline := getBaselineVersion(appliedMappedByVersion)
+	latestAppliedVersion := getLatestAppliedVersion(appliedMappedByVersion)
+	// Compare local and applied migrations version by version
+	res := make([]*MigrationInfo, 0)
+	for _, v := range getAllKeysSorted(localMappedByVersion, appliedMappedByVersion) {
+		l, hasLocal := localMappedByVersion[v]
+		a, hasApplied := appliedMappedByVersion[v]
+		info := &MigrationInfo{Version: v}
+		if hasLocal {
+			info.Description = l.Description
+			info.LocalChecksum, err = g.checksumOf(l)
+			if err != nil {
+				return nil, fmt.Errorf("failed to calculate checksum: %w", err)
+			}
+		}
+		if hasApplied {
+			info.Description = a.Description
+			info.AppliedChecksum = a.Checksum
+		}
+		switch {
+		case hasApplied && a.Kind == datasrc.KindBaseline:
+			info.State = StateBaseline
+		case hasApplied && !a.Success:
+			info.State = StateFailed
+		case !hasApplied && hasBaseline && v.Compare(baselineVersion) <= 0:
+			info.State = StateBelowBaseline
+		case !hasApplied && v.Less(latestAppliedVersion) && !g.outOfOrder:
+			info.State = StateOutOfOrder
+		case !hasApplied:
+			info.State = StatePending
+		case !hasLocal:
+			info.State = StateMissingLocally
+		case l.Description != a.Description:
+			info.State = StateDescriptionMismatch
+		case info.LocalChecksum != info.AppliedChecksum:
+			info.State = StateChecksumMismatch
+		default:
+			info.State = StateApplied
+		}
+		res = append(res, info)
+	}
+	// Compare repeatable migrations with their latest application
+	for _, l := range localRepeatables {
+		info := &MigrationInfo{Description: l.Description, Kind: migrsrc.KindRepeatable}
+		info.LocalChecksum, err = g.checksumOf(l)
 		if err != nil {
-			return fmt.Errorf("failed to apply migration %w", err)
+			return nil, fmt.Errorf("failed to calculate checksum: %w", err)
 		}
-		if !applied {
-			log.Panic("wtf...")
+		a, hasApplied := appliedRepeatables[l.Description]
+		switch {
+		case !hasApplied:
+			info.State = StatePending
+		case !a.Success:
+			info.AppliedChecksum = a.Checksum
+			info.State = StateFailed
+		case info.LocalChecksum != a.Checksum:
+			info.AppliedChecksum = a.Checksum
+			info.State = StateOutdated
+		default:
+			info.AppliedChecksum = a.Checksum
+			info.State = StateApplied
 		}
+		res = append(res, info)
+	}
+	return res, nil
+}
+
+// Validate compares local and applied migrations without locking the
+// datasource and returns a *ValidationError holding every problem found.
+func (g *G) Validate() error {
+	return g.ValidateContext(context.Background())
+}
+
+func (g *G) ValidateContext(ctx context.Context) error {
+	// Load local migrations and map them by version
+	localMappedByVersion, _, err := g.loadLocal()
+	if err != nil {
+		return err
+	}
+	// Load applied migrations and map them by version without locking
+	appliedMappedByVersion, _, err := g.loadApplied(ctx)
+	if err != nil {
+		return err
+	}
+	errs := g.validate(localMappedByVersion, appliedMappedByVersion)
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
 	}
-	log.Print("Datasource was successfully migrated!")
+	return nil
+}
+
+// Baseline marks every migration at or below version as applied on a
+// datasource without applied migrations.
+func (g *G) Baseline(v version.Version, description string) error {
+	return g.BaselineContext(context.Background(), v, description)
+}
+
+func (g *G) BaselineContext(ctx context.Context, v version.Version, description string) (err error) {
+	if g.dryRun {
+		return fmt.Errorf("%w: baseline", ErrDryRun)
+	}
+	g.logger.Info("Baselining datasource...", "version", v, "description", description)
+	v, err = version.Parse(string(v))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBaseline, err)
+	}
+	// Acquire datasource lock
+	err = g.ds.Lock(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to lock datasource: %w", err)
+	}
+	defer g.unlock(&err)
+	// Baselining is only allowed before any migration is applied
+	applied, err := g.ds.GetAppliedMigrations(ctx)
+	if err != nil {
+		return err
+	}
+	if len(applied) > 0 {
+		return fmt.Errorf("%w: datasource has %d applied migrations", ErrBaseline, len(applied))
+	}
+	err = g.ds.ApplyBaseline(ctx, v, description)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBaseline, err)
+	}
+	g.logger.Info("Datasource was successfully baselined!", "version", v, "description", description)
+	return nil
+}
+
+// Repair realigns the description and checksum of applied migrations with
+// their local migrations.
+func (g *G) Repair() error {
+	return g.RepairContext(context.Background())
+}
+
+func (g *G) RepairContext(ctx context.Context) (err error) {
+	if g.dryRun {
+		return fmt.Errorf("%w: repair", ErrDryRun)
+	}
+	g.logger.Info("Repairing datasource...")
+	// Load local migrations and map them by version
+	localMappedByVersion, _, err := g.loadLocal()
+	if err != nil {
+		return err
+	}
+	// Acquire datasource lock
+	err = g.ds.Lock(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to lock datasource: %w", err)
+	}
+	defer g.unlock(&err)
+	// Remove failed migrations so that they are applied again
+	err = g.ds.RemoveFailedMigrations(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to remove failed migrations: %w", err)
+	}
+	// Load applied migrations and map them by version
+	appliedMappedByVersion, _, err := g.loadApplied(ctx)
+	if err != nil {
+		return err
+	}
+	// Update applied migrations that differ from their local migration
+	repaired := 0
+	for _, v := range getAppliedKeysSorted(appliedMappedByVersion) {
+		a := appliedMappedByVersion[v]
+		l, ok := localMappedByVersion[v]
+		if !ok || a.Kind == datasrc.KindBaseline {
+			continue
+		}
+		checksum, err := g.checksumOf(l)
+		if err != nil {
+			return fmt.Errorf("failed to calculate checksum: %w", err)
+		}
+		if a.Description == l.Description && a.Checksum == checksum {
+			continue
+		}
+		err = g.ds.UpdateMigration(ctx, v, l.Description, checksum)
+		if err != nil {
+			return fmt.Errorf("failed to repair migration %s: %w", v, err)
+		}
+		g.logger.Info("Repaired migration",
+			"version", v,
+			"description", l.Description,
+			"checksum", checksum,
+			"previous_description", a.Description,
+			"previous_checksum", a.Checksum)
+		repaired++
+	}
+	g.logger.Info("Datasource was successfully repaired!", "repaired", repaired)
 	return nil
 }
 
 func (g *G) Clean() error {
-	log.Print("Cleaning datasource...")
-	err := g.ds.Lock()
+	return g.CleanContext(context.Background())
+}
+
+func (g *G) CleanContext(ctx context.Context) (err error) {
+	if g.dryRun {
+		return fmt.Errorf("%w: clean", ErrDryRun)
+	}
+	g.logger.Info("Cleaning datasource...")
+	err = g.ds.Lock(ctx)
 	if err != nil {
 		return err
 	}
-	defer g.ds.Unlock(err == nil)
-	err = g.ds.Clean()
+	defer g.unlock(&err)
+	err = g.runCallbacks(ctx, g.hooks.beforeClean, nil)
+	if err != nil {
+		return fmt.Errorf("before clean callback failed: %w", err)
+	}
+	err = g.ds.Clean(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to clean: %w", err)
 	}
-	log.Print("Datasource was successfully cleaned!")
+	err = g.runCallbacks(ctx, g.hooks.afterClean, nil)
+	if err != nil {
+		return fmt.Errorf("after clean callback failed: %w", err)
+	}
+	g.logger.Info("Datasource was successfully cleaned!")
+	return nil
+}
+
+// loadLocal returns the local versioned migrations mapped by version and the
+// local repeatable migrations ordered by description.
+func (g *G) loadLocal() (map[version.Version]*migrsrc.Migration, []*migrsrc.Migration, error) {
+	local, err := g.ms.Load()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+	local, err = getMigrationsWithCanonicalVersions(local)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = migrsrc.CheckDuplicates(local)
+	if err != nil {
+		return nil, nil, err
+	}
+	if g.placeholders != nil {
+		local, err = expandPlaceholders(local, g.placeholders)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	versioned, err := getLocalMigrationsMappedByVersion(local)
+	if err != nil {
+		return nil, nil, err
+	}
+	repeatables, err := getLocalRepeatableMigrationsSorted(local)
+	if err != nil {
+		return nil, nil, err
+	}
+	return versioned, repeatables, nil
+}
+
+// loadApplied returns the applied versioned migrations mapped by version and
+// the latest application of each repeatable migration mapped by description.
+func (g *G) loadApplied(ctx context.Context) (map[version.Version]*datasrc.Migration, map[string]*datasrc.Migration, error) {
+	applied, err := g.ds.GetAppliedMigrations(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	versioned, err := getDatasrcMigrationMappedByVersion(applied)
+	if err != nil {
+		return nil, nil, err
+	}
+	return versioned, getLatestRepeatableMigrationsMappedByDescription(applied), nil
+}
+
+// afterMigrate runs the after migrate error callbacks if *err is not nil. It
+// is deferred before unlock so that the callbacks run after the rollback.
+func (g *G) afterMigrate(ctx context.Context, err *error) {
+	if *err != nil {
+		g.runErrorCallbacks(ctx, *err)
+	}
+}
+
+// unlock releases the datasource lock and commits only if *err is nil.
+func (g *G) unlock(err *error) {
+	unlockErr := g.ds.Unlock(*err == nil)
+	if *err == nil && unlockErr != nil {
+		*err = fmt.Errorf("failed to unlock datasource: %w", unlockErr)
+	}
+}
+
+func (g *G) logPlan(ctx context.Context) error {
+	g.logger.Info("Planning datasource migration (dry run)...")
+	plan, err := g.PlanContext(ctx)
+	if err != nil {
+		return err
+	}
+	g.logPlanSteps(plan)
+	return nil
+}
+
+// logMigrateToPlan logs the migrations MigrateTo would undo or apply without
+// locking the datasource.
+func (g *G) logMigrateToPlan(ctx context.Context, target version.Version) error {
+	g.logger.Info("Planning datasource migration to target version (dry run)...", "target", target)
+	localMappedByVersion, _, err := g.loadLocal()
+	if err != nil {
+		return err
+	}
+	appliedMappedByVersion, _, err := g.loadApplied(ctx)
+	if err != nil {
+		return err
+	}
+	undoVersions, pending, err := g.getMigrateToSteps(localMappedByVersion, appliedMappedByVersion, target)
+	if err != nil {
+		return err
+	}
+	if len(undoVersions) > 0 {
+		return g.logUndoVersions(localMappedByVersion, appliedMappedByVersion, undoVersions)
+	}
+	plan, err := g.getPlan(pending)
+	if err != nil {
+		return err
+	}
+	g.logPlanSteps(plan)
+	return nil
+}
+
+// logUndoPlan logs the migrations Undo would undo without locking the
+// datasource.
+func (g *G) logUndoPlan(ctx context.Context, n int) error {
+	g.logger.Info("Planning undo of migrations (dry run)...", "count", n)
+	localMappedByVersion, _, err := g.loadLocal()
+	if err != nil {
+		return err
+	}
+	appliedMappedByVersion, _, err := g.loadApplied(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = g.getApplicableVersions(localMappedByVersion, appliedMappedByVersion)
+	if err != nil {
+		return err
+	}
+	undoVersions, err := getLatestAppliedVersions(appliedMappedByVersion, n)
+	if err != nil {
+		return err
+	}
+	return g.logUndoVersions(localMappedByVersion, appliedMappedByVersion, undoVersions)
+}
+
+func (g *G) logPlanSteps(plan *Plan) {
+	g.logger.Info("Would apply migrations", "count", len(plan.Steps))
+	for i, s := range plan.Steps {
+		g.logger.Info("Would apply migration",
+			"step", i+1,
+			"count", len(plan.Steps),
+			"version", s.Version,
+			"description", s.Description,
+			"checksum", s.Checksum,
+			"content", s.Content)
+	}
+}
+
+func (g *G) logUndoVersions(local map[version.Version]*migrsrc.Migration, applied map[version.Version]*datasrc.Migration, versions []version.Version) error {
+	err := checkUndoable(local, applied, versions)
+	if err != nil {
+		return err
+	}
+	g.logger.Info("Would undo migrations", "count", len(versions))
+	for i, v := range versions {
+		g.logger.Info("Would undo migration",
+			"step", i+1,
+			"count", len(versions),
+			"version", v,
+			"description", local[v].Description,
+			"content", local[v].UndoContent)
+	}
 	return nil
 }
 
-func (g *G) getApplicableVersions(local map[uint]*migrsrc.Migration, applied map[uint]*datasrc.Migration) ([]uint, error) {
-	matchingVersions := make([]uint, 0)
-	for version, a := range applied {
-		l, ok := local[version]
+// getPlan returns the plan of applying pending.
+func (g *G) getPlan(pending []*migrsrc.Migration) (*Plan, error) {
+	plan := &Plan{Steps: make([]*PlanStep, 0, len(pending))}
+	for _, m := range pending {
+		checksum, err := g.checksumOf(m)
+		if err != nil {
+			return nil, fmt.Errorf("failed to calculate checksum: %w", err)
+		}
+		plan.Steps = append(plan.Steps, &PlanStep{
+			Version:     m.Version,
+			Description: m.Description,
+			Checksum:    checksum,
+			Content:     m.Content,
+			Kind:        m.Kind,
+		})
+	}
+	return plan, nil
+}
+
+// getMigrateToSteps validates the migrations and returns the applied versions
+// above target, latest first, or if there are none the migrations to apply up
+// to target.
+func (g *G) getMigrateToSteps(local map[version.Version]*migrsrc.Migration, applied map[version.Version]*datasrc.Migration, target version.Version) ([]version.Version, []*migrsrc.Migration, error) {
+	applicableVersions, err := g.getApplicableVersions(local, applied)
+	if err != nil {
+		return nil, nil, err
+	}
+	appliedVersions := getAppliedKeysSorted(applied)
+	undoVersions := make([]version.Version, 0)
+	for i := len(appliedVersions) - 1; i >= 0 && target.Less(appliedVersions[i]); i-- {
+		undoVersions = append(undoVersions, appliedVersions[i])
+	}
+	if len(undoVersions) > 0 {
+		return undoVersions, nil, nil
+	}
+	return nil, getMigrationsByVersions(local, getVersionsUpTo(applicableVersions, target)), nil
+}
+
+// getPendingMigrations returns the versioned migrations to apply followed by
+// the outdated repeatable migrations.
+func (g *G) getPendingMigrations(local map[version.Version]*migrsrc.Migration, localRepeatables []*migrsrc.Migration, applied map[version.Version]*datasrc.Migration, appliedRepeatables map[string]*datasrc.Migration) ([]*migrsrc.Migration, error) {
+	pendingVersions, err := g.getPendingVersions(local, applied)
+	if err != nil {
+		return nil, err
+	}
+	pending := getMigrationsByVersions(local, pendingVersions)
+	for _, m := range localRepeatables {
+		checksum, err := g.checksumOf(m)
+		if err != nil {
+			return nil, fmt.Errorf("failed to calculate checksum: %w", err)
+		}
+		a, ok := appliedRepeatables[m.Description]
+		if !ok || !a.Success || a.Checksum != checksum {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// getPendingVersions returns the applicable versions up to the target version, if one is set.
+func (g *G) getPendingVersions(local map[version.Version]*migrsrc.Migration, applied map[version.Version]*datasrc.Migration) ([]version.Version, error) {
+	applicableVersions, err := g.getApplicableVersions(local, applied)
+	if err != nil {
+		return nil, err
+	}
+	if g.target != nil {
+		applicableVersions = getVersionsUpTo(applicableVersions, *g.target)
+	}
+	return applicableVersions, nil
+}
+
+func (g *G) getApplicableVersions(local map[version.Version]*migrsrc.Migration, applied map[version.Version]*datasrc.Migration) ([]version.Version, error) {
+	errs := g.validate(local, applied)
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	baselineVersion, hasBaseline := getBaselineVersion(applied)
+	applicableVersions := make([]version.Version, 0)
+	for _, v := range getKeysSorted(local) {
+		if _, ok := applied[v]; ok {
+			continue
+		}
+		if hasBaseline && v.Compare(baselineVersion) <= 0 {
+			continue
+		}
+		applicableVersions = append(applicableVersions, v)
+	}
+	return applicableVersions, nil
+}
+
+// validate returns every problem found when comparing local and applied migrations.
+// Local migrations at or below the baseline version are treated as applied.
+func (g *G) validate(local map[version.Version]*migrsrc.Migration, applied map[version.Version]*datasrc.Migration) []error {
+	errs := make([]error, 0)
+	baselineVersion, hasBaseline := getBaselineVersion(applied)
+	appliedVersions := getAppliedKeysSorted(applied)
+	for _, v := range appliedVersions {
+		a := applied[v]
+		if a.Kind == datasrc.KindBaseline {
+			continue
+		}
+		if !a.Success {
+			errs = append(errs, &FailedMigrationError{Version: v})
+			continue
+		}
+		l, ok := local[v]
 		if !ok {
-			return nil, fmt.Errorf("applied migration has no local migration: %d", version)
+			errs = append(errs, &MissingLocalMigrationError{Version: v})
+			continue
 		}
 		err := g.validateMigration(l, a)
 		if err != nil {
-			return nil, err
+			errs = append(errs, err)
 		}
-		matchingVersions = append(matchingVersions, version)
 	}
-	sort.Slice(matchingVersions, func(i, j int) bool {
-		return matchingVersions[i] < matchingVersions[j]
-	})
-	localVersions := getKeysSorted(local)
-	for i, v := range matchingVersions {
-		if localVersions[i] != v {
-			return nil, fmt.Errorf("encountered a local unapplied migration with a lower version than an already applied migration: %d vs %d", localVersions[i], v)
+	for _, v := range getKeysSorted(local) {
+		if _, ok := applied[v]; ok {
+			continue
+		}
+		if (hasBaseline && v.Compare(baselineVersion) <= 0) || g.outOfOrder {
+			continue
+		}
+		for _, appliedVersion := range appliedVersions {
+			if v.Less(appliedVersion) {
+				errs = append(errs, &OutOfOrderError{Version: v, AppliedVersion: appliedVersion})
+				break
+			}
 		}
 	}
-	return localVersions[len(matchingVersions):], nil
+	return errs
 }
 
 func (g *G) validateMigration(local *migrsrc.Migration, applied *datasrc.Migration) error {
@@ -128,24 +754,140 @@ func (g *G) validateMigration(local *migrsrc.Migration, applied *datasrc.Migrati
 		return fmt.Errorf("local version does not match applied version")
 	}
 	if local.Description != applied.Description {
-		return fmt.Errorf("local description does not match applied description")
+		return &DescriptionMismatchError{
+			Version:            local.Version,
+			LocalDescription:   local.Description,
+			AppliedDescription: applied.Description,
+		}
 	}
-	localChecksum, err := g.checksum(local.Content)
+	localChecksum, err := g.checksumOf(local)
 	if err != nil {
 		return fmt.Errorf("failed to calculate checksum: %w", err)
 	}
 	appliedChecksum := applied.Checksum
 	if localChecksum != appliedChecksum {
-		return fmt.Errorf("local checksum does not match applied checksum: %s != %s", localChecksum, appliedChecksum)
+		return &ChecksumMismatchError{
+			Version:         local.Version,
+			LocalChecksum:   localChecksum,
+			AppliedChecksum: appliedChecksum,
+		}
+	}
+	return nil
+}
+
+// applyMigrations applies migrations in order. Versioned migrations below
+// latestAppliedVersion are recorded as out of order.
+func (g *G) applyMigrations(ctx context.Context, migrations []*migrsrc.Migration, latestAppliedVersion version.Version) error {
+	g.logger.Info("Applying migrations...", "count", len(migrations))
+	for i, m := range migrations {
+		checksum, err := g.checksumOf(m)
+		if err != nil {
+			return fmt.Errorf("failed to calculate checksum: %w", err)
+		}
+		g.logger.Info("Applying migration...",
+			"step", i+1,
+			"count", len(migrations),
+			"version", m.Version,
+			"description", m.Description,
+			"kind", getDatasrcKind(m),
+			"checksum", checksum)
+		start := time.Now()
+		err = g.runCallbacks(ctx, g.hooks.beforeEachMigration, m)
+		if err == nil {
+			record := &datasrc.Migration{
+				Version:     m.Version,
+				Description: m.Description,
+				Checksum:    checksum,
+				Kind:        getDatasrcKind(m),
+				OutOfOrder:  m.Kind == migrsrc.KindVersioned && m.Version.Less(latestAppliedVersion),
+			}
+			switch {
+			case m.Func != nil && m.NoTransaction:
+				err = fmt.Errorf("Go migrations can not run outside of a transaction")
+			case m.Func != nil:
+				err = g.ds.ApplyFuncMigration(ctx, record, m.Func)
+			case m.NoTransaction:
+				err = g.ds.ApplyMigrationWithoutTx(ctx, record, m.Content)
+			default:
+				err = g.ds.ApplyMigration(ctx, record, m.Content)
+			}
+		}
+		if err == nil {
+			err = g.runCallbacks(ctx, g.hooks.afterEachMigration, m)
+		}
+		if err == nil && g.commitEach {
+			err = g.ds.Commit(ctx)
+		}
+		if err != nil {
+			g.logger.Error("Failed to apply migration",
+				"version", m.Version,
+				"description", m.Description,
+				"duration", time.Since(start),
+				"error", err)
+			return &MigrationError{Migration: m, Err: err}
+		}
+		g.logger.Info("Applied migration",
+			"version", m.Version,
+			"description", m.Description,
+			"duration", time.Since(start))
 	}
 	return nil
 }
 
-func (g *G) apply(m *migrsrc.Migration) (bool, error) {
-	checksum, err := g.checksum(m.Content)
+// undoVersions undoes the given versions in order, failing before any undo
+// script is executed if one of them lacks an undo script.
+func (g *G) undoVersions(ctx context.Context, local map[version.Version]*migrsrc.Migration, applied map[version.Version]*datasrc.Migration, versions []version.Version) error {
+	err := checkUndoable(local, applied, versions)
 	if err != nil {
-		return false, err
+		return err
+	}
+	for i, v := range versions {
+		g.logger.Info("Undoing migration...",
+			"step", i+1,
+			"count", len(versions),
+			"version", v,
+			"description", local[v].Description)
+		start := time.Now()
+		err := g.ds.UndoMigration(ctx, v, local[v].UndoContent)
+		if err != nil {
+			g.logger.Error("Failed to undo migration",
+				"version", v,
+				"description", local[v].Description,
+				"duration", time.Since(start),
+				"error", err)
+			return fmt.Errorf("failed to undo migration %s: %w", v, err)
+		}
+		g.logger.Info("Undid migration",
+			"version", v,
+			"description", local[v].Description,
+			"duration", time.Since(start))
+	}
+	return nil
+}
+
+// checkUndoable returns an error if one of versions is a baseline or lacks an
+// undo script.
+func checkUndoable(local map[version.Version]*migrsrc.Migration, applied map[version.Version]*datasrc.Migration, versions []version.Version) error {
+	for _, v := range versions {
+		if applied[v].Kind == datasrc.KindBaseline {
+			return fmt.Errorf("%w: can not undo baseline: %s", ErrUndo, v)
+		}
+		if local[v].UndoContent == "" {
+			return fmt.Errorf("%w: migration has no undo script: %s", ErrUndo, v)
+		}
+	}
+	return nil
+}
+
+// getLatestAppliedVersions returns the n latest applied versions, latest first.
+func getLatestAppliedVersions(applied map[version.Version]*datasrc.Migration, n int) ([]version.Version, error) {
+	appliedVersions := getAppliedKeysSorted(applied)
+	if n > len(appliedVersions) {
+		return nil, fmt.Errorf("%w: can not undo %d migrations when %d are applied", ErrUndo, n, len(appliedVersions))
+	}
+	undoVersions := make([]version.Version, 0, n)
+	for i := len(appliedVersions) - 1; i >= len(appliedVersions)-n; i-- {
+		undoVersions = append(undoVersions, appliedVersions[i])
 	}
-	err = g.ds.ApplyMigration(m.Version, m.Description, checksum, m.Content)
-	return true, err
+	return undoVersions, nil
 }
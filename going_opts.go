@@ -1,3 +1,64 @@
 package going
 
+import (
+	"github.com/mlu1109/going/logger"
+	"github.com/mlu1109/going/version"
+)
+
 type Option func(g *G)
+
+// WithTarget makes Migrate stop after the given version.
+func WithTarget(v version.Version) Option {
+	return func(g *G) {
+		g.target = &v
+	}
+}
+
+// WithDryRun makes Migrate, MigrateTo and Undo log their plan instead of
+// applying it. Baseline, Repair and Clean fail with ErrDryRun.
+func WithDryRun() Option {
+	return func(g *G) {
+		g.dryRun = true
+	}
+}
+
+// WithLogger replaces the default standard library logger.
+func WithLogger(l logger.Logger) Option {
+	return func(g *G) {
+		g.logger = l
+	}
+}
+
+// WithPlaceholders replaces ${name} in migration content with the given values
+// before checksumming and applying. Unknown placeholders are an error.
+func WithPlaceholders(placeholders map[string]string) Option {
+	return func(g *G) {
+		g.placeholders = placeholders
+	}
+}
+
+// WithCommitEachMigration makes Migrate commit after every migration instead
+// of once after all migrations, so a failure only rolls back the failing one.
+func WithCommitEachMigration() Option {
+	return func(g *G) {
+		g.commitEach = true
+	}
+}
+
+// WithOutOfOrder makes Migrate apply unapplied migrations with a lower version
+// than the latest applied one instead of failing. They are applied in version
+// order and recorded as out of order.
+func WithOutOfOrder() Option {
+	return func(g *G) {
+		g.outOfOrder = true
+	}
+}
+
+// WithChecksum replaces the default MD5 checksum. Changing the checksum of an
+// existing history table makes every applied migration mismatch until Repair
+// is run.
+func WithChecksum(fn Checksum) Option {
+	return func(g *G) {
+		g.checksum = fn
+	}
+}
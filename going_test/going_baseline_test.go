@@ -0,0 +1,58 @@
+package going_test
+
+import (
+	"testing"
+
+	"github.com/mlu1109/going/datasrc"
+	"github.com/mlu1109/going/version"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseline(t *testing.T) {
+
+	t.Run("Migrate after baseline", func(t *testing.T) {
+		// Given
+		g := NewTestGoing(valid_migrations)
+		_, err := db.Exec(`
+		create table test_table (
+			id  varchar(255) primary key,
+			num integer,
+			v2_added integer
+		);`)
+		assert.Nil(t, err)
+		err = g.Baseline("2", "Existing schema")
+		assert.Nil(t, err)
+		// When
+		err = g.Migrate()
+		// Then ...
+		assert.Nil(t, err)
+		// ... only migrations above the baseline were applied
+		applied, err := getAppliedMigrations()
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(applied))
+		kinds := make(map[version.Version]datasrc.Kind)
+		for _, a := range applied {
+			kinds[a.Version] = a.Kind
+		}
+		assert.Equal(t, map[version.Version]datasrc.Kind{"2": datasrc.KindBaseline, "4": datasrc.KindVersioned}, kinds)
+		// ... local migrations below the baseline are valid
+		err = g.Validate()
+		assert.Nil(t, err)
+	})
+
+	t.Run("Baseline datasource with applied migrations", func(t *testing.T) {
+		// Given
+		g := NewTestGoing(valid_migrations)
+		err := g.Migrate()
+		assert.Nil(t, err)
+		// When
+		err = g.Baseline("2", "Existing schema")
+		// Then ...
+		assert.NotNil(t, err)
+		// ... no baseline was written
+		applied, err := getAppliedMigrations()
+		assert.Nil(t, err)
+		assert.Equal(t, len(valid_migrations), len(applied))
+	})
+}
@@ -0,0 +1,40 @@
+package going_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mlu1109/going"
+	"github.com/mlu1109/going/migrsrc/slice"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecksum(t *testing.T) {
+
+	t.Run("Record checksum of the given algorithm", func(t *testing.T) {
+		// Given
+		NewTestGoing(nil)
+		g, err := going.New(slice.New(valid_migrations), ds, going.WithChecksum(going.SHA256ChecksumFn))
+		assert.Nil(t, err)
+		// When
+		err = g.Migrate()
+		// Then ...
+		assert.Nil(t, err)
+		applied, err := getAppliedMigrations()
+		assert.Nil(t, err)
+		expected, err := going.SHA256ChecksumFn(valid_migrations[0].Content)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, applied[0].Checksum)
+		// ... another algorithm mismatches until repaired
+		g, err = going.New(slice.New(valid_migrations), ds)
+		assert.Nil(t, err)
+		err = g.Validate()
+		var mismatch *going.ChecksumMismatchError
+		assert.True(t, errors.As(err, &mismatch))
+		err = g.Repair()
+		assert.Nil(t, err)
+		err = g.Validate()
+		assert.Nil(t, err)
+	})
+}
@@ -0,0 +1,109 @@
+package going_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/mlu1109/going"
+	"github.com/mlu1109/going/migrsrc"
+	"github.com/mlu1109/going/migrsrc/registry"
+	"github.com/mlu1109/going/migrsrc/slice"
+	"github.com/mlu1109/going/version"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func backfillNum(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, "update test_table set num = 42")
+	return err
+}
+
+func noopFunc(ctx context.Context, tx *sql.Tx) error {
+	return nil
+}
+
+func TestFuncMigrations(t *testing.T) {
+
+	t.Run("Apply Go migration alongside SQL migrations", func(t *testing.T) {
+		// Given
+		err := NewTestGoing(valid_migrations[:1]).Migrate()
+		assert.Nil(t, err)
+		_, err = db.Exec("insert into test_table (id) values ('1')")
+		assert.Nil(t, err)
+		ms := migrsrc.Combine(
+			slice.New(valid_migrations[:1]),
+			registry.New().Register("2", "backfill num", backfillNum),
+		)
+		g, err := going.New(ms, ds)
+		assert.Nil(t, err)
+		// When
+		err = g.Migrate()
+		// Then ...
+		assert.Nil(t, err)
+		// ... the Go migration ran
+		var num int
+		err = db.QueryRow("select num from test_table where id = '1'").Scan(&num)
+		assert.Nil(t, err)
+		assert.Equal(t, 42, num)
+		// ... the Go migration was recorded with a checksum of its version and description
+		applied, err := getAppliedMigrations()
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(applied))
+		assert.Equal(t, version.Version("2"), applied[1].Version)
+		checksum, err := going.DefaultChecksumFn("go:2:backfill num")
+		assert.Nil(t, err)
+		assert.Equal(t, checksum, applied[1].Checksum)
+		// ... and is valid on the next run
+		err = g.Validate()
+		assert.Nil(t, err)
+	})
+
+	t.Run("Changed revision of Go migration is a checksum mismatch", func(t *testing.T) {
+		// Given
+		NewTestGoing(nil)
+		g, err := going.New(registry.New().Register("1", "backfill num", noopFunc, "1"), ds)
+		assert.Nil(t, err)
+		err = g.Migrate()
+		assert.Nil(t, err)
+		applied, err := getAppliedMigrations()
+		assert.Nil(t, err)
+		checksum, err := going.DefaultChecksumFn("1")
+		assert.Nil(t, err)
+		assert.Equal(t, checksum, applied[0].Checksum)
+		// When
+		g, err = going.New(registry.New().Register("1", "backfill num", noopFunc, "2"), ds)
+		assert.Nil(t, err)
+		err = g.Validate()
+		// Then
+		var mismatch *going.ChecksumMismatchError
+		assert.ErrorAs(t, err, &mismatch)
+	})
+
+	t.Run("Failing Go migration is rolled back", func(t *testing.T) {
+		// Given
+		NewTestGoing(nil)
+		ms := migrsrc.Combine(
+			slice.New(valid_migrations[:1]),
+			registry.New().Register("2", "fail", func(ctx context.Context, tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, "insert into test_table (id) values ('1')")
+				if err != nil {
+					return err
+				}
+				return fmt.Errorf("backfill failed")
+			}),
+		)
+		g, err := going.New(ms, ds)
+		assert.Nil(t, err)
+		// When
+		err = g.Migrate()
+		// Then ...
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "backfill failed")
+		// ... nothing was applied
+		applied, err := getAppliedMigrations()
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(applied))
+	})
+}
@@ -0,0 +1,80 @@
+package going_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory(t *testing.T) {
+
+	t.Run("Record when, by whom and how long migrations ran", func(t *testing.T) {
+		// Given
+		g := NewTestGoing(valid_migrations)
+		// When
+		err := g.Migrate()
+		// Then ...
+		assert.Nil(t, err)
+		applied, err := getAppliedMigrations()
+		assert.Nil(t, err)
+		assert.Equal(t, len(valid_migrations), len(applied))
+		for i, a := range applied {
+			assert.Equal(t, applied[0].InstalledRank+i, a.InstalledRank)
+			assert.WithinDuration(t, time.Now(), a.InstalledOn, time.Minute)
+			assert.Equal(t, user, a.InstalledBy)
+			assert.GreaterOrEqual(t, int64(a.ExecutionTime), int64(0))
+			assert.True(t, a.Success)
+		}
+	})
+
+	t.Run("Upgrade history table without audit columns", func(t *testing.T) {
+		// Given
+		g := NewTestGoing(valid_migrations)
+		err := g.Migrate()
+		assert.Nil(t, err)
+		_, err = db.Exec(`alter table going_schema_history
+			drop column installed_on, drop column installed_by,
+			drop column execution_time_ms, drop column success`)
+		assert.Nil(t, err)
+		// When
+		ctx := context.Background()
+		err = ds.Lock(ctx)
+		assert.Nil(t, err)
+		err = ds.Init(ctx)
+		assert.Nil(t, ds.Unlock(err == nil))
+		// Then ...
+		assert.Nil(t, err)
+		// ... existing records are kept as successful
+		applied, err := getAppliedMigrations()
+		assert.Nil(t, err)
+		assert.Equal(t, len(valid_migrations), len(applied))
+		assert.True(t, applied[0].Success)
+		assert.True(t, applied[0].InstalledOn.IsZero())
+	})
+}
+
+func TestHistoryVersionType(t *testing.T) {
+
+	t.Run("Upgrade integer version column to text", func(t *testing.T) {
+		// Given
+		g := NewTestGoing(valid_migrations)
+		err := g.Migrate()
+		assert.Nil(t, err)
+		_, err = db.Exec("drop index going_schema_history_version_idx")
+		assert.Nil(t, err)
+		_, err = db.Exec("alter table going_schema_history alter column version type integer using version::integer")
+		assert.Nil(t, err)
+		// When
+		ctx := context.Background()
+		err = ds.Lock(ctx)
+		assert.Nil(t, err)
+		err = ds.Init(ctx)
+		assert.Nil(t, ds.Unlock(err == nil))
+		// Then ...
+		assert.Nil(t, err)
+		err = g.Validate()
+		assert.Nil(t, err)
+	})
+}
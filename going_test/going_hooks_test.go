@@ -0,0 +1,105 @@
+package going_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/mlu1109/going"
+	"github.com/mlu1109/going/migrsrc"
+	"github.com/mlu1109/going/migrsrc/slice"
+	"github.com/mlu1109/going/version"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHooks(t *testing.T) {
+
+	t.Run("Run callbacks around each migration in the active transaction", func(t *testing.T) {
+		// Given
+		NewTestGoing(valid_migrations)
+		var versions []version.Version
+		g, err := going.New(slice.New(valid_migrations), ds,
+			going.WithBeforeMigrate(func(ctx context.Context, tx *sql.Tx, m *migrsrc.Migration) error {
+				_, err := tx.ExecContext(ctx, "create table hook_table ( version integer );")
+				return err
+			}),
+			going.WithAfterEachMigration(func(ctx context.Context, tx *sql.Tx, m *migrsrc.Migration) error {
+				versions = append(versions, m.Version)
+				_, err := tx.ExecContext(ctx, "insert into hook_table (version) values ($1);", m.Version)
+				return err
+			}),
+		)
+		assert.Nil(t, err)
+		// When
+		err = g.Migrate()
+		// Then ...
+		assert.Nil(t, err)
+		assert.Equal(t, []version.Version{"1", "2", "4"}, versions)
+		// ... callbacks were committed with the migrations
+		var count int
+		err = db.QueryRow("select count(*) from hook_table").Scan(&count)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, count)
+	})
+
+	t.Run("Run error callback with failing migration", func(t *testing.T) {
+		// Given
+		NewTestGoing(valid_migrations)
+		invalid_migrations := append(valid_migrations[:2:2], &migrsrc.Migration{Version: "4", Description: "invalid", Content: "invalid"})
+		var failed *migrsrc.Migration
+		g, err := going.New(slice.New(invalid_migrations), ds,
+			going.WithAfterMigrateError(func(ctx context.Context, m *migrsrc.Migration, err error) {
+				failed = m
+			}),
+		)
+		assert.Nil(t, err)
+		// When
+		err = g.Migrate()
+		// Then ...
+		assert.NotNil(t, err)
+		assert.Equal(t, invalid_migrations[2], failed)
+		// ... migrations were rolled back
+		applied, err := getAppliedMigrations()
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(applied))
+	})
+
+	t.Run("Skip error callback with invalid target", func(t *testing.T) {
+		// Given
+		NewTestGoing(valid_migrations)
+		called := false
+		g, err := going.New(slice.New(valid_migrations), ds,
+			going.WithAfterMigrateError(func(ctx context.Context, m *migrsrc.Migration, err error) {
+				called = true
+			}),
+		)
+		assert.Nil(t, err)
+		// When
+		err = g.MigrateTo("v2")
+		// Then
+		assert.ErrorIs(t, err, version.ErrInvalid)
+		assert.False(t, called)
+	})
+
+	t.Run("Run callbacks around clean", func(t *testing.T) {
+		// Given
+		var calls []string
+		g, err := going.New(slice.New(valid_migrations), ds,
+			going.WithBeforeClean(func(ctx context.Context, tx *sql.Tx, m *migrsrc.Migration) error {
+				calls = append(calls, "before")
+				return nil
+			}),
+			going.WithAfterClean(func(ctx context.Context, tx *sql.Tx, m *migrsrc.Migration) error {
+				calls = append(calls, "after")
+				return nil
+			}),
+		)
+		assert.Nil(t, err)
+		// When
+		err = g.Clean()
+		// Then
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"before", "after"}, calls)
+	})
+}
@@ -0,0 +1,45 @@
+package going_test
+
+import (
+	"testing"
+
+	"github.com/mlu1109/going"
+	"github.com/mlu1109/going/migrsrc"
+	"github.com/mlu1109/going/migrsrc/slice"
+	"github.com/mlu1109/going/version"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInfo(t *testing.T) {
+
+	t.Run("Report state of each migration", func(t *testing.T) {
+		// Given
+		NewTestGoing(valid_migrations)
+		g, err := going.New(slice.New(valid_migrations[1:]), ds)
+		assert.Nil(t, err)
+		err = g.Migrate()
+		assert.Nil(t, err)
+		local := []*migrsrc.Migration{
+			valid_migrations[0],
+			{Version: "2", Description: valid_migrations[1].Description, Content: "modified"},
+			{Version: "5", Description: "Migration V5", Content: "select 1;"},
+		}
+		g, err = going.New(slice.New(local), ds)
+		assert.Nil(t, err)
+		// When
+		info, err := g.Info()
+		// Then ...
+		assert.Nil(t, err)
+		states := make(map[version.Version]going.State)
+		for _, i := range info {
+			states[i.Version] = i.State
+		}
+		assert.Equal(t, map[version.Version]going.State{
+			"1": going.StateOutOfOrder,
+			"2": going.StateChecksumMismatch,
+			"4": going.StateMissingLocally,
+			"5": going.StatePending,
+		}, states)
+	})
+}
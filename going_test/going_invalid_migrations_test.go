@@ -1,11 +1,13 @@
 package going_test
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/mlu1109/going"
 	"github.com/mlu1109/going/migrsrc"
 	"github.com/mlu1109/going/migrsrc/slice"
+	"github.com/mlu1109/going/version"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -18,7 +20,7 @@ func TestMigrateWithInvalidMigrations(t *testing.T) {
 		err := g.Migrate()
 		assert.Nil(t, err)
 		// When
-		invalid_migrations := append(valid_migrations, &migrsrc.Migration{Version: 3, Description: "invalid", Content: "invalid"})
+		invalid_migrations := append(valid_migrations, &migrsrc.Migration{Version: "3", Description: "invalid", Content: "invalid"})
 		g, err = going.New(slice.New(invalid_migrations), ds)
 		assert.Nil(t, err)
 		err = g.Migrate()
@@ -63,7 +65,7 @@ func TestMigrateWithInvalidMigrations(t *testing.T) {
 		for _, test := range tests {
 			// Given
 			migrations := []*migrsrc.Migration{
-				migrsrc.NewMigration(1, "Description", "create table test ( id int primary key );"),
+				migrsrc.NewMigration("1", "Description", "create table test ( id int primary key );"),
 			}
 			g := NewTestGoing(migrations)
 			err := g.Migrate()
@@ -80,3 +82,50 @@ func TestMigrateWithInvalidMigrations(t *testing.T) {
 		}
 	})
 }
+
+func TestValidate(t *testing.T) {
+
+	t.Run("Report every problem", func(t *testing.T) {
+		// Given
+		g := NewTestGoing(valid_migrations)
+		err := g.Migrate()
+		assert.Nil(t, err)
+		invalid_migrations := []*migrsrc.Migration{
+			{Version: "1", Description: "modified", Content: valid_migrations[0].Content},
+			{Version: "2", Description: valid_migrations[1].Description, Content: "modified"},
+			{Version: "3", Description: "invalid", Content: "invalid"},
+		}
+		g, err = going.New(slice.New(invalid_migrations), ds)
+		assert.Nil(t, err)
+		// When
+		err = g.Validate()
+		// Then ...
+		var validationErr *going.ValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		assert.Equal(t, 4, len(validationErr.Errors))
+		// ... each problem can be matched by type
+		var descriptionErr *going.DescriptionMismatchError
+		assert.True(t, errors.As(err, &descriptionErr))
+		assert.Equal(t, version.Version("1"), descriptionErr.Version)
+		var checksumErr *going.ChecksumMismatchError
+		assert.True(t, errors.As(err, &checksumErr))
+		assert.Equal(t, version.Version("2"), checksumErr.Version)
+		var missingErr *going.MissingLocalMigrationError
+		assert.True(t, errors.As(err, &missingErr))
+		assert.Equal(t, version.Version("4"), missingErr.Version)
+		var outOfOrderErr *going.OutOfOrderError
+		assert.True(t, errors.As(err, &outOfOrderErr))
+		assert.Equal(t, version.Version("3"), outOfOrderErr.Version)
+	})
+
+	t.Run("Valid migrations", func(t *testing.T) {
+		// Given
+		g := NewTestGoing(valid_migrations)
+		err := g.Migrate()
+		assert.Nil(t, err)
+		// When
+		err = g.Validate()
+		// Then
+		assert.Nil(t, err)
+	})
+}
@@ -0,0 +1,77 @@
+package going_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mlu1109/going"
+	"github.com/mlu1109/going/datasrc/postgres"
+	"github.com/mlu1109/going/migrsrc/slice"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLock(t *testing.T) {
+
+	t.Run("Time out while another process holds the lock", func(t *testing.T) {
+		// Given
+		other := postgres.New(postgres.WithDB(db), postgres.WithSchema(search_path, true))
+		waiting := postgres.New(postgres.WithDB(db), postgres.WithSchema(search_path, true),
+			postgres.WithLockTimeout(0))
+		err := other.Lock(context.Background())
+		assert.Nil(t, err)
+		defer other.Unlock(false)
+		// When
+		err = waiting.Lock(context.Background())
+		// Then ...
+		assert.ErrorIs(t, err, postgres.ErrLockTimeout)
+	})
+
+	t.Run("Fail to open while another process holds the lock", func(t *testing.T) {
+		// Given
+		other := postgres.New(postgres.WithDB(db), postgres.WithSchema(search_path, true))
+		err := other.Lock(context.Background())
+		assert.Nil(t, err)
+		defer other.Unlock(false)
+		// When
+		waiting, err := postgres.Open(postgres.WithDB(db), postgres.WithSchema(search_path, true),
+			postgres.WithLockTimeout(0))
+		// Then ...
+		assert.ErrorIs(t, err, postgres.ErrLockTimeout)
+		assert.Nil(t, waiting)
+	})
+
+	t.Run("Concurrent migrations wait and apply once", func(t *testing.T) {
+		// Given
+		NewTestGoing(nil)
+		var wg sync.WaitGroup
+		errs := make([]error, 2)
+		// When
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				replica, err := postgres.Open(postgres.WithDB(db), postgres.WithSchema(search_path, true),
+					postgres.WithLockTimeout(10*time.Second))
+				var g *going.G
+				if err == nil {
+					g, err = going.New(slice.New(valid_migrations), replica)
+				}
+				if err == nil {
+					err = g.Migrate()
+				}
+				errs[i] = err
+			}(i)
+		}
+		wg.Wait()
+		// Then ...
+		assert.Nil(t, errs[0])
+		assert.Nil(t, errs[1])
+		// ... every migration was applied once
+		applied, err := getAppliedMigrations()
+		assert.Nil(t, err)
+		assert.Equal(t, len(valid_migrations), len(applied))
+	})
+}
@@ -0,0 +1,37 @@
+package going_test
+
+import (
+	"testing"
+
+	"github.com/mlu1109/going"
+	"github.com/mlu1109/going/migrsrc"
+	"github.com/mlu1109/going/migrsrc/slice"
+	"github.com/mlu1109/going/version"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutOfOrder(t *testing.T) {
+
+	t.Run("Apply skipped version with out of order enabled", func(t *testing.T) {
+		// Given
+		g := NewTestGoing([]*migrsrc.Migration{valid_migrations[0], valid_migrations[2]})
+		err := g.Migrate()
+		assert.Nil(t, err)
+		// When
+		g, err = going.New(slice.New(valid_migrations), ds, going.WithOutOfOrder())
+		assert.Nil(t, err)
+		err = g.Migrate()
+		// Then ...
+		assert.Nil(t, err)
+		// ... the skipped version was applied and marked out of order
+		applied, err := getAppliedMigrations()
+		assert.Nil(t, err)
+		assert.Equal(t, 3, len(applied))
+		assert.Equal(t, version.Version("2"), applied[2].Version)
+		assert.True(t, applied[2].OutOfOrder)
+		// ... and validates
+		err = g.Validate()
+		assert.Nil(t, err)
+	})
+}
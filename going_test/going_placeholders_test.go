@@ -0,0 +1,61 @@
+package going_test
+
+import (
+	"testing"
+
+	"github.com/mlu1109/going"
+	"github.com/mlu1109/going/migrsrc"
+	"github.com/mlu1109/going/migrsrc/slice"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var placeholder_migrations = []*migrsrc.Migration{
+	{
+		Version:     "1",
+		Description: "Migration V1",
+		Content: `
+		create table ${table} (
+			id  varchar(255) primary key
+		);`,
+		Source: "migrations/V1__Migration_V1.sql",
+	},
+}
+
+func TestPlaceholders(t *testing.T) {
+
+	t.Run("Expand placeholders before applying", func(t *testing.T) {
+		// Given
+		NewTestGoing(nil)
+		g, err := going.New(slice.New(placeholder_migrations), ds,
+			going.WithPlaceholders(map[string]string{"table": "test_table"}))
+		assert.Nil(t, err)
+		// When
+		err = g.Migrate()
+		// Then ...
+		assert.Nil(t, err)
+		_, err = db.Exec("insert into test_table (id) values ('1')")
+		assert.Nil(t, err)
+		// ... the expanded content was checksummed
+		err = g.Validate()
+		assert.Nil(t, err)
+	})
+
+	t.Run("Fail on unresolved placeholder", func(t *testing.T) {
+		// Given
+		NewTestGoing(nil)
+		g, err := going.New(slice.New(placeholder_migrations), ds,
+			going.WithPlaceholders(map[string]string{}))
+		assert.Nil(t, err)
+		// When
+		err = g.Migrate()
+		// Then ...
+		var placeholderErr *going.UnresolvedPlaceholderError
+		assert.ErrorAs(t, err, &placeholderErr)
+		assert.Equal(t, "unresolved placeholder ${table} in migrations/V1__Migration_V1.sql", err.Error())
+		// ... nothing was applied
+		applied, err := getAppliedMigrations()
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(applied))
+	})
+}
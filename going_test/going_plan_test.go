@@ -0,0 +1,108 @@
+package going_test
+
+import (
+	"testing"
+
+	"github.com/mlu1109/going"
+	"github.com/mlu1109/going/migrsrc/slice"
+	"github.com/mlu1109/going/version"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlan(t *testing.T) {
+
+	t.Run("Plan pending migrations", func(t *testing.T) {
+		// Given
+		NewTestGoing(valid_migrations)
+		g, err := going.New(slice.New(valid_migrations[:1]), ds)
+		assert.Nil(t, err)
+		err = g.Migrate()
+		assert.Nil(t, err)
+		g, err = going.New(slice.New(valid_migrations), ds)
+		assert.Nil(t, err)
+		// When
+		plan, err := g.Plan()
+		// Then ...
+		assert.Nil(t, err)
+		// ... plan contains the pending migrations
+		assert.Equal(t, []version.Version{"2", "4"}, plan.Versions())
+		for i, step := range plan.Steps {
+			expected := valid_migrations[i+1]
+			expectedChecksum, _ := going.DefaultChecksumFn(expected.Content)
+			assert.Equal(t, expected.Description, step.Description)
+			assert.Equal(t, expectedChecksum, step.Checksum)
+			assert.Equal(t, expected.Content, step.Content)
+		}
+		// ... pending migrations were not applied
+		applied, err := getAppliedMigrations()
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(applied))
+	})
+
+	t.Run("Migrate with dry run", func(t *testing.T) {
+		// Given
+		NewTestGoing(valid_migrations)
+		g, err := going.New(slice.New(valid_migrations), ds, going.WithDryRun())
+		assert.Nil(t, err)
+		// When
+		err = g.Migrate()
+		// Then ...
+		assert.Nil(t, err)
+		// ... migrations were not applied
+		applied, err := getAppliedMigrations()
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(applied))
+	})
+
+	t.Run("Migrate to target with dry run", func(t *testing.T) {
+		// Given
+		g := NewTestGoing(undoable_migrations)
+		err := g.Migrate()
+		assert.Nil(t, err)
+		g, err = going.New(slice.New(undoable_migrations), ds, going.WithDryRun())
+		assert.Nil(t, err)
+		// When
+		err = g.MigrateTo("1")
+		// Then ...
+		assert.Nil(t, err)
+		// ... migrations were not undone
+		applied, err := getAppliedMigrations()
+		assert.Nil(t, err)
+		assert.Equal(t, 3, len(applied))
+	})
+
+	t.Run("Undo with dry run", func(t *testing.T) {
+		// Given
+		g := NewTestGoing(undoable_migrations)
+		err := g.Migrate()
+		assert.Nil(t, err)
+		g, err = going.New(slice.New(undoable_migrations), ds, going.WithDryRun())
+		assert.Nil(t, err)
+		// When
+		err = g.Undo(2)
+		// Then ...
+		assert.Nil(t, err)
+		// ... migrations were not undone
+		applied, err := getAppliedMigrations()
+		assert.Nil(t, err)
+		assert.Equal(t, 3, len(applied))
+	})
+
+	t.Run("Refuse to clean with dry run", func(t *testing.T) {
+		// Given
+		g := NewTestGoing(valid_migrations)
+		err := g.Migrate()
+		assert.Nil(t, err)
+		g, err = going.New(slice.New(valid_migrations), ds, going.WithDryRun())
+		assert.Nil(t, err)
+		// When
+		err = g.Clean()
+		// Then ...
+		assert.ErrorIs(t, err, going.ErrDryRun)
+		// ... migrations are still applied
+		applied, err := getAppliedMigrations()
+		assert.Nil(t, err)
+		assert.Equal(t, 3, len(applied))
+	})
+}
@@ -0,0 +1,40 @@
+package going_test
+
+import (
+	"testing"
+
+	"github.com/mlu1109/going"
+	"github.com/mlu1109/going/migrsrc"
+	"github.com/mlu1109/going/migrsrc/slice"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepair(t *testing.T) {
+
+	t.Run("Realign modified migrations", func(t *testing.T) {
+		// Given
+		migrations := []*migrsrc.Migration{
+			migrsrc.NewMigration("1", "Description", "create table test ( id int primary key );"),
+		}
+		g := NewTestGoing(migrations)
+		err := g.Migrate()
+		assert.Nil(t, err)
+		migrations[0].Description = "Modified"
+		migrations[0].Content = "create table test (id int primary key);"
+		g, err = going.New(slice.New(migrations), ds)
+		assert.Nil(t, err)
+		assert.NotNil(t, g.Validate())
+		// When
+		err = g.Repair()
+		// Then ...
+		assert.Nil(t, err)
+		// ... history matches the local migrations
+		assert.Nil(t, g.Validate())
+		applied, err := getAppliedMigrations()
+		assert.Nil(t, err)
+		expectedChecksum, _ := going.DefaultChecksumFn(migrations[0].Content)
+		assert.Equal(t, "Modified", applied[0].Description)
+		assert.Equal(t, expectedChecksum, applied[0].Checksum)
+	})
+}
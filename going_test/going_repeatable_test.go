@@ -0,0 +1,46 @@
+package going_test
+
+import (
+	"testing"
+
+	"github.com/mlu1109/going"
+	"github.com/mlu1109/going/datasrc"
+	"github.com/mlu1109/going/migrsrc"
+	"github.com/mlu1109/going/migrsrc/slice"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepeatableMigrations(t *testing.T) {
+
+	t.Run("Re-apply repeatable migration when its checksum changes", func(t *testing.T) {
+		// Given
+		view := migrsrc.NewRepeatableMigration("test_view", "create or replace view test_view as select id from test_table;")
+		migrations := append(valid_migrations[:3:3], view)
+		g := NewTestGoing(migrations)
+		err := g.Migrate()
+		assert.Nil(t, err)
+		// When
+		err = g.Migrate()
+		// Then ...
+		assert.Nil(t, err)
+		// ... unchanged repeatable migration was applied once
+		applied, err := getAppliedMigrations()
+		assert.Nil(t, err)
+		assert.Equal(t, len(valid_migrations)+1, len(applied))
+		assert.Equal(t, datasrc.KindRepeatable, applied[len(applied)-1].Kind)
+		// When
+		view.Content = "create or replace view test_view as select id, num from test_table;"
+		g, err = going.New(slice.New(migrations), ds)
+		assert.Nil(t, err)
+		err = g.Migrate()
+		// Then ...
+		assert.Nil(t, err)
+		// ... changed repeatable migration was applied again
+		applied, err = getAppliedMigrations()
+		assert.Nil(t, err)
+		assert.Equal(t, len(valid_migrations)+2, len(applied))
+		_, err = db.Exec("select num from test_view")
+		assert.Nil(t, err)
+	})
+}
@@ -1,6 +1,7 @@
 package going_test
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
@@ -62,12 +63,13 @@ func NewTestGoing(migrations []*migrsrc.Migration) *going.G {
 }
 
 func getAppliedMigrations() ([]*datasrc.Migration, error) {
-	err := ds.Lock()
+	ctx := context.Background()
+	err := ds.Lock(ctx)
 	if err != nil {
 		return nil, err
 	}
 	defer ds.Unlock(false)
-	applied, err := ds.GetAppliedMigrations()
+	applied, err := ds.GetAppliedMigrations(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,128 @@
+package going_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mlu1109/going"
+	"github.com/mlu1109/going/migrsrc"
+	"github.com/mlu1109/going/migrsrc/slice"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransactionModes(t *testing.T) {
+
+	t.Run("Create index concurrently outside of the transaction", func(t *testing.T) {
+		// Given
+		index := &migrsrc.Migration{
+			Version:       "5",
+			Description:   "Migration V5",
+			Content:       "create index concurrently test_table_num_idx on test_table (num);",
+			NoTransaction: true,
+		}
+		g := NewTestGoing(append(valid_migrations[:3:3], index))
+		// When
+		err := g.Migrate()
+		// Then ...
+		assert.Nil(t, err)
+		applied, err := getAppliedMigrations()
+		assert.Nil(t, err)
+		assert.Equal(t, 4, len(applied))
+		assert.True(t, applied[3].Success)
+	})
+
+	t.Run("Create several indexes concurrently outside of the transaction", func(t *testing.T) {
+		// Given
+		indexes := &migrsrc.Migration{
+			Version:     "5",
+			Description: "Migration V5",
+			Content: migrsrc.DirectiveNoTransaction + `
+			create index concurrently test_table_num_idx on test_table (num);
+			create index concurrently test_table_v2_added_idx on test_table (v2_added);`,
+			NoTransaction: true,
+		}
+		g := NewTestGoing(append(valid_migrations[:3:3], indexes))
+		// When
+		err := g.Migrate()
+		// Then ...
+		assert.Nil(t, err)
+		// ... both indexes were created
+		var count int
+		err = db.QueryRow("select count(*) from pg_indexes where tablename = 'test_table' and indexname in ('test_table_num_idx', 'test_table_v2_added_idx')").Scan(&count)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, count)
+	})
+
+	t.Run("Record failed migration outside of the transaction", func(t *testing.T) {
+		// Given
+		failing := &migrsrc.Migration{
+			Version:       "5",
+			Description:   "Migration V5",
+			Content:       "create index concurrently test_table_num_idx on missing_table (num);",
+			NoTransaction: true,
+		}
+		g := NewTestGoing(append(valid_migrations[:3:3], failing))
+		// When
+		err := g.Migrate()
+		// Then ...
+		assert.NotNil(t, err)
+		// ... the failed migration was recorded
+		applied, err := getAppliedMigrations()
+		assert.Nil(t, err)
+		assert.Equal(t, 4, len(applied))
+		assert.False(t, applied[3].Success)
+		// ... and blocks migrating until repaired
+		err = g.Migrate()
+		var failedErr *going.FailedMigrationError
+		assert.ErrorAs(t, err, &failedErr)
+		err = g.Repair()
+		assert.Nil(t, err)
+		applied, err = getAppliedMigrations()
+		assert.Nil(t, err)
+		assert.Equal(t, 3, len(applied))
+	})
+
+	t.Run("Record migration outside of the transaction cancelled while running", func(t *testing.T) {
+		// Given
+		sleeping := &migrsrc.Migration{
+			Version:       "5",
+			Description:   "Migration V5",
+			Content:       "create index concurrently test_table_num_idx on test_table (num); select pg_sleep(10);",
+			NoTransaction: true,
+		}
+		g := NewTestGoing(append(valid_migrations[:3:3], sleeping))
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		// When
+		err := g.MigrateContext(ctx)
+		// Then ...
+		assert.NotNil(t, err)
+		// ... the failed migration was recorded despite the cancellation
+		applied, err := getAppliedMigrations()
+		assert.Nil(t, err)
+		assert.Equal(t, 4, len(applied))
+		assert.False(t, applied[3].Success)
+		// ... and blocks migrating until repaired
+		err = g.Migrate()
+		var failedErr *going.FailedMigrationError
+		assert.ErrorAs(t, err, &failedErr)
+	})
+
+	t.Run("Commit after each migration", func(t *testing.T) {
+		// Given
+		NewTestGoing(nil)
+		invalid_migrations := append(valid_migrations[:2:2], &migrsrc.Migration{Version: "4", Description: "invalid", Content: "invalid"})
+		g, err := going.New(slice.New(invalid_migrations), ds, going.WithCommitEachMigration())
+		assert.Nil(t, err)
+		// When
+		err = g.Migrate()
+		// Then ...
+		assert.NotNil(t, err)
+		// ... migrations before the failing one were committed
+		applied, err := getAppliedMigrations()
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(applied))
+	})
+}
@@ -0,0 +1,124 @@
+package going_test
+
+import (
+	"testing"
+
+	"github.com/mlu1109/going/migrsrc"
+	"github.com/mlu1109/going/version"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var undoable_migrations = []*migrsrc.Migration{
+	{
+		Version:     "1",
+		Description: "Migration V1",
+		Content: `
+		create table test_table (
+			id  varchar(255) primary key,
+			num integer
+		);`,
+		UndoContent: `
+		drop table test_table;`,
+	},
+	{
+		Version:     "2",
+		Description: "Migration V2",
+		Content: `
+		alter table test_table add column v2_added integer;`,
+		UndoContent: `
+		alter table test_table drop column v2_added;`,
+	},
+	{
+		Version:     "4",
+		Description: "Migration V4",
+		Content: `
+		alter table test_table add column v3_added text;`,
+		UndoContent: `
+		alter table test_table drop column v3_added;`,
+	},
+}
+
+func TestUndoMigrations(t *testing.T) {
+
+	t.Run("Undo latest migrations", func(t *testing.T) {
+		// Given
+		g := NewTestGoing(undoable_migrations)
+		err := g.Migrate()
+		assert.Nil(t, err)
+		// When
+		err = g.Undo(2)
+		// Then ...
+		assert.Nil(t, err)
+		// ... only the first migration remains applied
+		applied, err := getAppliedMigrations()
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(applied))
+		assert.Equal(t, version.Version("1"), applied[0].Version)
+		// ... undone columns were dropped
+		_, err = db.Exec("insert into test_table (id, num) values ('1', 1)")
+		assert.Nil(t, err)
+		_, err = db.Exec("insert into test_table (id, num, v2_added) values ('2', 1, 2)")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Undo more migrations than applied", func(t *testing.T) {
+		// Given
+		g := NewTestGoing(undoable_migrations)
+		err := g.Migrate()
+		assert.Nil(t, err)
+		// When
+		err = g.Undo(4)
+		// Then ...
+		assert.NotNil(t, err)
+		// ... migrations are still applied
+		applied, err := getAppliedMigrations()
+		assert.Nil(t, err)
+		assert.Equal(t, 3, len(applied))
+	})
+
+	t.Run("Undo migration without undo script", func(t *testing.T) {
+		// Given
+		g := NewTestGoing(valid_migrations)
+		err := g.Migrate()
+		assert.Nil(t, err)
+		// When
+		err = g.Undo(1)
+		// Then ...
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "migration has no undo script: 4")
+		// ... migrations are still applied
+		applied, err := getAppliedMigrations()
+		assert.Nil(t, err)
+		assert.Equal(t, 3, len(applied))
+	})
+}
+
+func TestMigrateTo(t *testing.T) {
+
+	t.Run("Migrate up to target", func(t *testing.T) {
+		// Given
+		g := NewTestGoing(undoable_migrations)
+		// When
+		err := g.MigrateTo("2")
+		// Then ...
+		assert.Nil(t, err)
+		applied, err := getAppliedMigrations()
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(applied))
+	})
+
+	t.Run("Migrate down to target", func(t *testing.T) {
+		// Given
+		g := NewTestGoing(undoable_migrations)
+		err := g.Migrate()
+		assert.Nil(t, err)
+		// When
+		err = g.MigrateTo("0")
+		// Then ...
+		assert.Nil(t, err)
+		applied, err := getAppliedMigrations()
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(applied))
+	})
+}
@@ -1,17 +1,19 @@
 package going_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/mlu1109/going"
 	"github.com/mlu1109/going/migrsrc"
+	"github.com/mlu1109/going/migrsrc/slice"
 
 	"github.com/stretchr/testify/assert"
 )
 
 var valid_migrations = []*migrsrc.Migration{
 	{
-		Version:     1,
+		Version:     "1",
 		Description: "Migration V1",
 		Content: `
 		create table test_table (
@@ -20,13 +22,13 @@ var valid_migrations = []*migrsrc.Migration{
 		);`,
 	},
 	{
-		Version:     2,
+		Version:     "2",
 		Description: "Migration V2",
 		Content: `
 		alter table test_table add column v2_added integer;`,
 	},
 	{
-		Version:     4,
+		Version:     "4",
 		Description: "Migration V4",
 		Content: `
 		alter table test_table add column v3_added text;`,
@@ -95,3 +97,55 @@ func TestMigrateWithValidMigrations(t *testing.T) {
 		assert.Equal(t, "1", id)
 	})
 }
+
+func TestMigrateWithTarget(t *testing.T) {
+
+	t.Run("Apply migrations up to target", func(t *testing.T) {
+		// Given
+		NewTestGoing(valid_migrations)
+		g, err := going.New(slice.New(valid_migrations), ds, going.WithTarget("2"))
+		assert.Nil(t, err)
+		// When
+		err = g.Migrate()
+		// Then ...
+		assert.Nil(t, err)
+		applied, err := getAppliedMigrations()
+		assert.Nil(t, err)
+		// ... migrations after target were not applied
+		assert.Equal(t, 2, len(applied))
+		// ... remaining migrations are applied without target
+		g, err = going.New(slice.New(valid_migrations), ds)
+		assert.Nil(t, err)
+		err = g.Migrate()
+		assert.Nil(t, err)
+		applied, err = getAppliedMigrations()
+		assert.Nil(t, err)
+		assert.Equal(t, len(valid_migrations), len(applied))
+	})
+
+	t.Run("Reject invalid target", func(t *testing.T) {
+		// When
+		_, err := going.New(slice.New(valid_migrations), ds, going.WithTarget("v2"))
+		// Then
+		assert.ErrorIs(t, err, going.ErrInitiaization)
+	})
+}
+
+func TestMigrateContext(t *testing.T) {
+
+	t.Run("Migrate with cancelled context", func(t *testing.T) {
+		// Given
+		g := NewTestGoing(valid_migrations)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		// When
+		err := g.MigrateContext(ctx)
+		// Then ...
+		assert.NotNil(t, err)
+		assert.ErrorIs(t, err, context.Canceled)
+		// ... migrations were not applied
+		applied, err := getAppliedMigrations()
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(applied))
+	})
+}
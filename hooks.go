@@ -0,0 +1,90 @@
+package going
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/mlu1109/going/migrsrc"
+)
+
+// Callback is called with the active transaction. The migration is nil for
+// callbacks that are not tied to a single migration.
+type Callback func(ctx context.Context, tx *sql.Tx, m *migrsrc.Migration) error
+
+// ErrorCallback is called after a failed migrate has been rolled back. The
+// migration is nil if the failure was not caused by a single migration.
+type ErrorCallback func(ctx context.Context, m *migrsrc.Migration, err error)
+
+type hooks struct {
+	beforeMigrate       []Callback
+	beforeEachMigration []Callback
+	afterEachMigration  []Callback
+	afterMigrateError   []ErrorCallback
+	beforeClean         []Callback
+	afterClean          []Callback
+}
+
+func WithBeforeMigrate(cb Callback) Option {
+	return func(g *G) {
+		g.hooks.beforeMigrate = append(g.hooks.beforeMigrate, cb)
+	}
+}
+
+func WithBeforeEachMigration(cb Callback) Option {
+	return func(g *G) {
+		g.hooks.beforeEachMigration = append(g.hooks.beforeEachMigration, cb)
+	}
+}
+
+func WithAfterEachMigration(cb Callback) Option {
+	return func(g *G) {
+		g.hooks.afterEachMigration = append(g.hooks.afterEachMigration, cb)
+	}
+}
+
+func WithAfterMigrateError(cb ErrorCallback) Option {
+	return func(g *G) {
+		g.hooks.afterMigrateError = append(g.hooks.afterMigrateError, cb)
+	}
+}
+
+func WithBeforeClean(cb Callback) Option {
+	return func(g *G) {
+		g.hooks.beforeClean = append(g.hooks.beforeClean, cb)
+	}
+}
+
+func WithAfterClean(cb Callback) Option {
+	return func(g *G) {
+		g.hooks.afterClean = append(g.hooks.afterClean, cb)
+	}
+}
+
+func (g *G) runCallbacks(ctx context.Context, callbacks []Callback, m *migrsrc.Migration) error {
+	if len(callbacks) == 0 {
+		return nil
+	}
+	tx, err := g.ds.Tx()
+	if err != nil {
+		return err
+	}
+	for _, cb := range callbacks {
+		err = cb(ctx, tx, m)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *G) runErrorCallbacks(ctx context.Context, err error) {
+	var m *migrsrc.Migration
+	var migrationErr *MigrationError
+	if errors.As(err, &migrationErr) {
+		m = migrationErr.Migration
+	}
+	for _, cb := range g.hooks.afterMigrateError {
+		cb(ctx, m, err)
+	}
+}
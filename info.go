@@ -0,0 +1,30 @@
+package going
+
+import (
+	"github.com/mlu1109/going/migrsrc"
+	"github.com/mlu1109/going/version"
+)
+
+type State string
+
+const (
+	StatePending             State = "pending"
+	StateApplied             State = "applied"
+	StateChecksumMismatch    State = "checksum-mismatch"
+	StateDescriptionMismatch State = "description-mismatch"
+	StateMissingLocally      State = "missing-locally"
+	StateOutOfOrder          State = "out-of-order"
+	StateBaseline            State = "baseline"
+	StateBelowBaseline       State = "below-baseline"
+	StateOutdated            State = "outdated"
+	StateFailed              State = "failed"
+)
+
+type MigrationInfo struct {
+	Version         version.Version
+	Description     string
+	State           State
+	LocalChecksum   string
+	AppliedChecksum string
+	Kind            migrsrc.Kind
+}
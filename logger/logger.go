@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Logger is implemented by *slog.Logger. Args are alternating keys and values.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+type std struct {
+	l *log.Logger
+}
+
+// Std returns a Logger printing "LEVEL msg key=value ..." lines through l,
+// or through the standard logger if l is nil.
+func Std(l *log.Logger) Logger {
+	if l == nil {
+		l = log.Default()
+	}
+	return &std{l}
+}
+
+func (s *std) Debug(msg string, args ...interface{}) {
+	s.print("DEBUG", msg, args)
+}
+
+func (s *std) Info(msg string, args ...interface{}) {
+	s.print("INFO", msg, args)
+}
+
+func (s *std) Warn(msg string, args ...interface{}) {
+	s.print("WARN", msg, args)
+}
+
+func (s *std) Error(msg string, args ...interface{}) {
+	s.print("ERROR", msg, args)
+}
+
+func (s *std) print(level string, msg string, args []interface{}) {
+	var sb strings.Builder
+	sb.WriteString(level)
+	sb.WriteString(" ")
+	sb.WriteString(msg)
+	for i := 0; i < len(args); i += 2 {
+		var key, value interface{}
+		if i+1 < len(args) {
+			key, value = args[i], args[i+1]
+		} else {
+			key, value = "!BADKEY", args[i]
+		}
+		sb.WriteString(fmt.Sprintf(" %v=%s", key, formatValue(value)))
+	}
+	s.l.Print(sb.String())
+}
+
+func formatValue(value interface{}) string {
+	s := fmt.Sprint(value)
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return fmt.Sprintf("%q", s)
+	}
+	return s
+}
+
+type nop struct{}
+
+// Nop returns a Logger discarding everything.
+func Nop() Logger {
+	return nop{}
+}
+
+func (nop) Debug(msg string, args ...interface{}) {}
+func (nop) Info(msg string, args ...interface{})  {}
+func (nop) Warn(msg string, args ...interface{})  {}
+func (nop) Error(msg string, args ...interface{}) {}
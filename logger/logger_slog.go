@@ -0,0 +1,15 @@
+//go:build go1.21
+
+package logger
+
+import "log/slog"
+
+// Slog returns a Logger backed by l, or by slog.Default() if l is nil.
+func Slog(l *slog.Logger) Logger {
+	if l == nil {
+		l = slog.Default()
+	}
+	return l
+}
+
+var _ Logger = (*slog.Logger)(nil)
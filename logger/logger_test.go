@@ -0,0 +1,28 @@
+package logger
+
+import (
+	"bytes"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStd_whenLogging_thenPrintLevelMessageAndFields(t *testing.T) {
+	tests := []struct {
+		args     []interface{}
+		expected string
+	}{
+		{[]interface{}{}, "INFO Applying migration\n"},
+		{[]interface{}{"version", 1, "description", "add users"}, "INFO Applying migration version=1 description=\"add users\"\n"},
+		{[]interface{}{"duration", 2 * time.Second}, "INFO Applying migration duration=2s\n"},
+		{[]interface{}{"version"}, "INFO Applying migration !BADKEY=version\n"},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		l := Std(log.New(&buf, "", 0))
+		l.Info("Applying migration", test.args...)
+		assert.Equal(t, test.expected, buf.String())
+	}
+}
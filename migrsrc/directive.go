@@ -0,0 +1,18 @@
+package migrsrc
+
+import "strings"
+
+// DirectiveNoTransaction on a line of its own in a migration file sets
+// NoTransaction. On Postgres the statements of such a migration are executed
+// one at a time, since a query of several statements runs in an implicit
+// transaction.
+const DirectiveNoTransaction = "-- going:no-transaction"
+
+// ParseDirectives sets the fields of m given by directives in its content.
+func ParseDirectives(m *Migration) {
+	for _, line := range strings.Split(m.Content, "\n") {
+		if strings.TrimSpace(line) == DirectiveNoTransaction {
+			m.NoTransaction = true
+		}
+	}
+}
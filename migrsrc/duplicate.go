@@ -0,0 +1,47 @@
+package migrsrc
+
+import (
+	"fmt"
+
+	"github.com/mlu1109/going/version"
+)
+
+// DuplicateError is returned when two versioned migrations share a version or
+// two repeatable migrations share a description.
+type DuplicateError struct {
+	First  *Migration
+	Second *Migration
+}
+
+func (e *DuplicateError) Error() string {
+	var what string
+	if e.First.Kind == KindRepeatable {
+		what = fmt.Sprintf("encountered duplicate repeatable migration: %s", e.First.Description)
+	} else {
+		what = fmt.Sprintf("encountered duplicate version: %s", e.First.Version)
+	}
+	if e.First.Source == "" || e.Second.Source == "" {
+		return what
+	}
+	return fmt.Sprintf("%s (%s and %s)", what, e.First.Source, e.Second.Source)
+}
+
+// CheckDuplicates returns a *DuplicateError for the first duplicate found.
+func CheckDuplicates(migrations []*Migration) error {
+	versions := make(map[version.Version]*Migration)
+	descriptions := make(map[string]*Migration)
+	for _, m := range migrations {
+		if m.Kind == KindRepeatable {
+			if first, ok := descriptions[m.Description]; ok {
+				return &DuplicateError{First: first, Second: m}
+			}
+			descriptions[m.Description] = m
+			continue
+		}
+		if first, ok := versions[m.Version]; ok {
+			return &DuplicateError{First: first, Second: m}
+		}
+		versions[m.Version] = m
+	}
+	return nil
+}
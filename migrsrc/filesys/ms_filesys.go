@@ -1,88 +1,52 @@
 package filesys
 
 import (
-	"errors"
-	"fmt"
 	"os"
-	"regexp"
-	"strconv"
-	"strings"
+	"path/filepath"
 
 	"github.com/mlu1109/going/migrsrc"
+	"github.com/mlu1109/going/migrsrc/iofs"
 )
 
 type MS struct {
-	path string
+	paths     []string
+	recursive bool
 }
 
-func New(path string) *MS {
-	return &MS{path}
+func New(path string, options ...Option) *MS {
+	ms := &MS{paths: []string{path}}
+	for _, option := range options {
+		option(ms)
+	}
+	return ms
 }
 
+// Load loads the migrations of every path. Versions must be unique across paths.
 func (d *MS) Load() ([]*migrsrc.Migration, error) {
-	entries, err := os.ReadDir(d.path)
-	if err != nil {
-		return nil, err
+	var options []iofs.Option
+	if d.recursive {
+		options = append(options, iofs.WithRecursive())
 	}
 	var migrations []*migrsrc.Migration
-	for _, entry := range entries {
-		fn := entry.Name()
-		if entry.IsDir() || !strings.HasSuffix(fn, ".sql") {
-			continue
-		}
-		fp := fmt.Sprintf("%s/%s", d.path, fn)
-		migration, err := getMigrationFromFile(fp)
+	for _, path := range d.paths {
+		loaded, err := iofs.New(os.DirFS(path), ".", options...).Load()
 		if err != nil {
 			return nil, err
 		}
-		migrations = append(migrations, migration)
-	}
-	return migrations, nil
-}
-
-func getMigrationFromFile(path string) (*migrsrc.Migration, error) {
-	fn := getFileName(path)
-	version, description, err := parseFileName(fn)
-	if err != nil {
-		return nil, err
+		for _, m := range loaded {
+			m.Source = filepath.Join(path, filepath.FromSlash(m.Source))
+		}
+		migrations = append(migrations, loaded...)
 	}
-	bytes, err := os.ReadFile(path)
+	err := migrsrc.CheckDuplicates(migrations)
 	if err != nil {
 		return nil, err
 	}
-	content := string(bytes)
-	return migrsrc.NewMigration(version, description, content), nil
-}
-
-var ErrInvalidFileName = errors.New("invalid filename")
-var ErrInvalidVersion = errors.New("invalid version")
-var ErrInvalidDescription = errors.New("invalid description")
-
-func parseFileName(fn string) (uint, string, error) {
-	var matcher = regexp.MustCompile(`V(?P<version>\d+)__(?P<description>.+).sql`)
-	matches := matcher.FindAllSubmatch([]byte(fn), -1)
-	if len(matches) != 1 || len(matches[0]) != 3 {
-		return 0, "", ErrInvalidFileName
-	}
-	version := matches[0][1]
-	description := matches[0][2]
-	if len(version) == 0 {
-		return 0, "", ErrInvalidVersion
-	} else if len(description) == 0 {
-		return 0, "", ErrInvalidDescription
-	}
-	v, err := strconv.ParseUint(string(version), 10, 0)
-	if err != nil {
-		return 0, "", fmt.Errorf("%s: %w", ErrInvalidVersion, err)
-	}
-	return uint(v), string(description), nil
+	return migrations, nil
 }
 
-func getFileName(path string) string {
-	parts := strings.Split(path, "/")
-	if len(parts) == 1 {
-		return path
-	} else {
-		return parts[len(parts)-1]
-	}
-}
+var ErrInvalidFileName = iofs.ErrInvalidFileName
+var ErrInvalidVersion = iofs.ErrInvalidVersion
+var ErrInvalidDescription = iofs.ErrInvalidDescription
+var ErrUnmatchedUndo = iofs.ErrUnmatchedUndo
+var ErrDuplicateUndo = iofs.ErrDuplicateUndo
@@ -2,8 +2,16 @@ package filesys
 
 type Option func(ms *MS)
 
+// WithPath adds another directory to load migrations from.
 func WithPath(path string) Option {
 	return func(ms *MS) {
-		ms.path = path
+		ms.paths = append(ms.paths, path)
+	}
+}
+
+// WithRecursive makes Load descend into subdirectories of every path.
+func WithRecursive() Option {
+	return func(ms *MS) {
+		ms.recursive = true
 	}
 }
@@ -0,0 +1,115 @@
+package filesys
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mlu1109/going/version"
+)
+
+type ScaffoldOption func(s *scaffold)
+
+type scaffold struct {
+	timestamp time.Time
+	undo      bool
+}
+
+// WithTimestamp versions the new migration by t, e.g. 20261018143000, instead
+// of the next major version. Timestamps avoid collisions between branches.
+func WithTimestamp(t time.Time) ScaffoldOption {
+	return func(s *scaffold) {
+		s.timestamp = t
+	}
+}
+
+// WithUndo also creates an empty undo migration.
+func WithUndo() ScaffoldOption {
+	return func(s *scaffold) {
+		s.undo = true
+	}
+}
+
+// Scaffold creates an empty migration for description in the first path and
+// returns the paths of the created files. The version is one major version
+// above the latest loaded migration unless WithTimestamp is given.
+func (d *MS) Scaffold(description string, options ...ScaffoldOption) ([]string, error) {
+	s := &scaffold{}
+	for _, option := range options {
+		option(s)
+	}
+	name := fileDescription(description)
+	if name == "" {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidDescription, description)
+	}
+	migrations, err := d.Load()
+	if err != nil {
+		return nil, err
+	}
+	var latest version.Version
+	for _, m := range migrations {
+		if latest.Less(m.Version) {
+			latest = m.Version
+		}
+	}
+	var v version.Version
+	if s.timestamp.IsZero() {
+		v = nextMajorVersion(latest)
+	} else {
+		v = version.MustParse(s.timestamp.Format("20060102150405"))
+		if !latest.Less(v) {
+			return nil, fmt.Errorf("%w: %s is not above the latest version %s", ErrInvalidVersion, v, latest)
+		}
+	}
+	files := []string{filepath.Join(d.paths[0], versionPrefix+v.String()+"__"+name+".sql")}
+	if s.undo {
+		files = append(files, filepath.Join(d.paths[0], undoPrefix+v.String()+"__"+name+".sql"))
+	}
+	for i, fp := range files {
+		err = createFile(fp, fmt.Sprintf("-- %s\n", description))
+		if err != nil {
+			for _, created := range files[:i] {
+				os.Remove(created)
+			}
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+const (
+	versionPrefix = "V"
+	undoPrefix    = "U"
+)
+
+var nonAlphanumeric = regexp.MustCompile(`[^a-z0-9]+`)
+
+// fileDescription turns description into lower snake case.
+func fileDescription(description string) string {
+	return strings.Trim(nonAlphanumeric.ReplaceAllString(strings.ToLower(description), "_"), "_")
+}
+
+func nextMajorVersion(v version.Version) version.Version {
+	if v == "" {
+		return version.FromUint(1)
+	}
+	major, _ := strconv.ParseUint(strings.SplitN(v.String(), ".", 2)[0], 10, 64)
+	return version.Version(strconv.FormatUint(major+1, 10))
+}
+
+func createFile(fp string, content string) error {
+	f, err := os.OpenFile(fp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(content)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
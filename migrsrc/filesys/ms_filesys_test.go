@@ -1,41 +1,75 @@
 package filesys
 
 import (
-	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/mlu1109/going/migrsrc"
 
 	"github.com/stretchr/testify/assert"
 )
 
-func TestParseFileName_whenValid_thenReturnExpectedPartsAndNoError(t *testing.T) {
-	tests := []struct {
-		input               string
-		expectedVersion     uint
-		expectedDescription string
-	}{
-		{"V2__this_is_version_2.sql", 2, "this_is_version_2"},
-		{"V3__this__is__version_3.sql", 3, "this__is__version_3"},
-	}
-	for _, test := range tests {
-		actualVersion, actualDescription, actualError := parseFileName(test.input)
-		assert.Equal(t, test.expectedVersion, actualVersion)
-		assert.Equal(t, test.expectedDescription, actualDescription)
-		assert.Nil(t, actualError)
-	}
+func TestLoad_whenValid_thenReturnMigrationsFromDirectory(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "V1__create_table.sql"), []byte("create table test ( id int );"), 0644)
+	assert.Nil(t, err)
+	migrations, err := New(dir).Load()
+	assert.Nil(t, err)
+	assert.Equal(t, []*migrsrc.Migration{
+		{Version: "1", Description: "create_table", Content: "create table test ( id int );", Source: filepath.Join(dir, "V1__create_table.sql")},
+	}, migrations)
+}
+
+func TestLoad_whenDuplicateVersionAcrossPaths_thenReturnErrorWithBothFiles(t *testing.T) {
+	billing := t.TempDir()
+	auth := t.TempDir()
+	err := os.WriteFile(filepath.Join(billing, "V1__create_invoices.sql"), []byte("create table invoices ( id int );"), 0644)
+	assert.Nil(t, err)
+	err = os.MkdirAll(filepath.Join(auth, "nested"), 0755)
+	assert.Nil(t, err)
+	err = os.WriteFile(filepath.Join(auth, "nested", "V1__create_users.sql"), []byte("create table users ( id int );"), 0644)
+	assert.Nil(t, err)
+	// Subdirectories are skipped unless recursive
+	migrations, err := New(billing, WithPath(auth)).Load()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(migrations))
+	// Duplicates across paths are reported with both files
+	_, err = New(billing, WithPath(auth), WithRecursive()).Load()
+	var duplicateErr *migrsrc.DuplicateError
+	assert.ErrorAs(t, err, &duplicateErr)
+	assert.Equal(t, filepath.Join(billing, "V1__create_invoices.sql"), duplicateErr.First.Source)
+	assert.Equal(t, filepath.Join(auth, "nested", "V1__create_users.sql"), duplicateErr.Second.Source)
+}
+
+func TestScaffold_whenMigrationsExist_thenCreateNextVersion(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "V1_2__create_table.sql"), []byte("create table test ( id int );"), 0644)
+	assert.Nil(t, err)
+	files, err := New(dir).Scaffold("Add users' e-mail", WithUndo())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "V2__add_users_e_mail.sql"),
+		filepath.Join(dir, "U2__add_users_e_mail.sql"),
+	}, files)
+	// The created files load as a migration with an undo migration
+	migrations, err := New(dir).Load()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(migrations))
+	assert.Equal(t, "add_users_e_mail", migrations[1].Description)
+	assert.Equal(t, "-- Add users' e-mail\n", migrations[1].UndoContent)
 }
 
-func TestParseFileName_whenInvalid_thenReturnBlanksAndError(t *testing.T) {
-	tests := []struct {
-		input         string
-		expectedError error
-	}{
-		{"V__this_is_version_2.sql", fmt.Errorf("invalid filename")},
-		{"V_2_1__this__is__version_2_1.sql", fmt.Errorf("invalid filename")},
-		{"VA__can't_be___arsed_to_write_version.sql", fmt.Errorf("invalid filename")},
-	}
-	for _, test := range tests {
-		_, actualDescription, actualError := parseFileName(test.input)
-		assert.Len(t, actualDescription, 0)
-		assert.Equal(t, test.expectedError, actualError)
-	}
+func TestScaffold_whenTimestamp_thenVersionByTimestamp(t *testing.T) {
+	dir := t.TempDir()
+	files, err := New(dir).Scaffold("create table", WithTimestamp(time.Date(2026, 10, 18, 14, 30, 0, 0, time.UTC)))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "V20261018143000__create_table.sql")}, files)
+	// A timestamp not above the latest version is rejected
+	_, err = New(dir).Scaffold("create table", WithTimestamp(time.Date(2026, 10, 18, 14, 30, 0, 0, time.UTC)))
+	assert.ErrorIs(t, err, ErrInvalidVersion)
+	// A description without letters or digits is rejected
+	_, err = New(dir).Scaffold("--")
+	assert.ErrorIs(t, err, ErrInvalidDescription)
 }
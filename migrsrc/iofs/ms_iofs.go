@@ -0,0 +1,205 @@
+package iofs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/mlu1109/going/migrsrc"
+	"github.com/mlu1109/going/version"
+)
+
+// MS loads migrations from a directory of an fs.FS, e.g. an embed.FS.
+type MS struct {
+	fsys      fs.FS
+	dir       string
+	recursive bool
+}
+
+func New(fsys fs.FS, dir string, options ...Option) *MS {
+	ms := &MS{fsys: fsys, dir: dir}
+	for _, option := range options {
+		option(ms)
+	}
+	return ms
+}
+
+func (d *MS) Load() ([]*migrsrc.Migration, error) {
+	var migrations []*migrsrc.Migration
+	var undoMigrations []*migrsrc.Migration
+	err := fs.WalkDir(d.fsys, d.dir, func(fp string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		fn := entry.Name()
+		if entry.IsDir() {
+			if fp != d.dir && !d.recursive {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(fn, ".sql") {
+			return nil
+		}
+		if strings.HasPrefix(fn, repeatablePrefix) {
+			migration, err := getRepeatableMigrationFromFile(d.fsys, fp)
+			if err != nil {
+				return err
+			}
+			migrations = append(migrations, migration)
+			return nil
+		}
+		if strings.HasPrefix(fn, undoPrefix) {
+			undo, err := getUndoMigrationFromFile(d.fsys, fp)
+			if err != nil {
+				return err
+			}
+			undoMigrations = append(undoMigrations, undo)
+			return nil
+		}
+		migration, err := getMigrationFromFile(d.fsys, fp)
+		if err != nil {
+			return err
+		}
+		migrations = append(migrations, migration)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = migrsrc.CheckDuplicates(migrations)
+	if err != nil {
+		return nil, err
+	}
+	err = attachUndoMigrations(migrations, undoMigrations)
+	if err != nil {
+		return nil, err
+	}
+	return migrations, nil
+}
+
+func attachUndoMigrations(migrations []*migrsrc.Migration, undoMigrations []*migrsrc.Migration) error {
+	for _, undo := range undoMigrations {
+		var match *migrsrc.Migration
+		for _, m := range migrations {
+			if m.Kind == migrsrc.KindVersioned && m.Version == undo.Version {
+				match = m
+				break
+			}
+		}
+		if match == nil {
+			return fmt.Errorf("%w: %s", ErrUnmatchedUndo, undo.Version)
+		}
+		if match.UndoContent != "" {
+			return fmt.Errorf("%w: %s", ErrDuplicateUndo, undo.Version)
+		}
+		match.UndoContent = undo.Content
+	}
+	return nil
+}
+
+func getMigrationFromFile(fsys fs.FS, fp string) (*migrsrc.Migration, error) {
+	fn := path.Base(fp)
+	version, description, err := parseFileName(fn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fp, err)
+	}
+	bytes, err := fs.ReadFile(fsys, fp)
+	if err != nil {
+		return nil, err
+	}
+	content := string(bytes)
+	m := migrsrc.NewMigration(version, description, content)
+	m.Source = fp
+	migrsrc.ParseDirectives(m)
+	return m, nil
+}
+
+func getUndoMigrationFromFile(fsys fs.FS, fp string) (*migrsrc.Migration, error) {
+	fn := path.Base(fp)
+	version, description, err := parseUndoFileName(fn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fp, err)
+	}
+	bytes, err := fs.ReadFile(fsys, fp)
+	if err != nil {
+		return nil, err
+	}
+	content := string(bytes)
+	m := migrsrc.NewMigration(version, description, content)
+	m.Source = fp
+	return m, nil
+}
+
+func getRepeatableMigrationFromFile(fsys fs.FS, fp string) (*migrsrc.Migration, error) {
+	fn := path.Base(fp)
+	description, err := parseRepeatableFileName(fn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fp, err)
+	}
+	bytes, err := fs.ReadFile(fsys, fp)
+	if err != nil {
+		return nil, err
+	}
+	content := string(bytes)
+	m := migrsrc.NewRepeatableMigration(description, content)
+	m.Source = fp
+	migrsrc.ParseDirectives(m)
+	return m, nil
+}
+
+var ErrInvalidFileName = errors.New("invalid filename")
+var ErrInvalidVersion = errors.New("invalid version")
+var ErrInvalidDescription = errors.New("invalid description")
+var ErrUnmatchedUndo = errors.New("undo migration has no matching migration")
+var ErrDuplicateUndo = errors.New("encountered duplicate undo migration")
+
+const (
+	versionPrefix    = "V"
+	undoPrefix       = "U"
+	repeatablePrefix = "R__"
+)
+
+func parseFileName(fn string) (version.Version, string, error) {
+	return parsePrefixedFileName(versionPrefix, fn)
+}
+
+func parseUndoFileName(fn string) (version.Version, string, error) {
+	return parsePrefixedFileName(undoPrefix, fn)
+}
+
+func parseRepeatableFileName(fn string) (string, error) {
+	var matcher = regexp.MustCompile(`R__(?P<description>.+).sql`)
+	matches := matcher.FindAllSubmatch([]byte(fn), -1)
+	if len(matches) != 1 || len(matches[0]) != 2 {
+		return "", ErrInvalidFileName
+	}
+	description := matches[0][1]
+	if len(description) == 0 {
+		return "", ErrInvalidDescription
+	}
+	return string(description), nil
+}
+
+func parsePrefixedFileName(prefix string, fn string) (version.Version, string, error) {
+	var matcher = regexp.MustCompile(prefix + `(?P<version>\d+(?:[._]\d+)*)__(?P<description>.+).sql`)
+	matches := matcher.FindAllSubmatch([]byte(fn), -1)
+	if len(matches) != 1 || len(matches[0]) != 3 {
+		return "", "", ErrInvalidFileName
+	}
+	v := matches[0][1]
+	description := matches[0][2]
+	if len(v) == 0 {
+		return "", "", ErrInvalidVersion
+	} else if len(description) == 0 {
+		return "", "", ErrInvalidDescription
+	}
+	parsed, err := version.Parse(string(v))
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", ErrInvalidVersion, err)
+	}
+	return parsed, string(description), nil
+}
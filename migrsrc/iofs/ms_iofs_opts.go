@@ -0,0 +1,10 @@
+package iofs
+
+type Option func(ms *MS)
+
+// WithRecursive makes Load descend into subdirectories.
+func WithRecursive() Option {
+	return func(ms *MS) {
+		ms.recursive = true
+	}
+}
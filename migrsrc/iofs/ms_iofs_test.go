@@ -0,0 +1,149 @@
+package iofs
+
+import (
+	"fmt"
+	"testing"
+	"testing/fstest"
+
+	"github.com/mlu1109/going/migrsrc"
+	"github.com/mlu1109/going/version"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFileName_whenValid_thenReturnExpectedPartsAndNoError(t *testing.T) {
+	tests := []struct {
+		input               string
+		expectedVersion     version.Version
+		expectedDescription string
+	}{
+		{"V2__this_is_version_2.sql", "2", "this_is_version_2"},
+		{"V3__this__is__version_3.sql", "3", "this__is__version_3"},
+		{"V1.2.1__dotted.sql", "1.2.1", "dotted"},
+		{"V1_2_1__underscored.sql", "1.2.1", "underscored"},
+		{"V20261018143000__timestamp.sql", "20261018143000", "timestamp"},
+	}
+	for _, test := range tests {
+		actualVersion, actualDescription, actualError := parseFileName(test.input)
+		assert.Equal(t, test.expectedVersion, actualVersion)
+		assert.Equal(t, test.expectedDescription, actualDescription)
+		assert.Nil(t, actualError)
+	}
+}
+
+func TestParseFileName_whenInvalid_thenReturnBlanksAndError(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError error
+	}{
+		{"V__this_is_version_2.sql", fmt.Errorf("invalid filename")},
+		{"V_2_1__this__is__version_2_1.sql", fmt.Errorf("invalid filename")},
+		{"VA__can't_be___arsed_to_write_version.sql", fmt.Errorf("invalid filename")},
+	}
+	for _, test := range tests {
+		_, actualDescription, actualError := parseFileName(test.input)
+		assert.Len(t, actualDescription, 0)
+		assert.Equal(t, test.expectedError, actualError)
+	}
+}
+
+func TestParseUndoFileName_whenValid_thenReturnExpectedPartsAndNoError(t *testing.T) {
+	tests := []struct {
+		input               string
+		expectedVersion     version.Version
+		expectedDescription string
+	}{
+		{"U2__this_is_version_2.sql", "2", "this_is_version_2"},
+		{"U3__this__is__version_3.sql", "3", "this__is__version_3"},
+	}
+	for _, test := range tests {
+		actualVersion, actualDescription, actualError := parseUndoFileName(test.input)
+		assert.Equal(t, test.expectedVersion, actualVersion)
+		assert.Equal(t, test.expectedDescription, actualDescription)
+		assert.Nil(t, actualError)
+	}
+}
+
+func TestParseUndoFileName_whenVersionedFileName_thenReturnError(t *testing.T) {
+	_, actualDescription, actualError := parseUndoFileName("V2__this_is_version_2.sql")
+	assert.Len(t, actualDescription, 0)
+	assert.Equal(t, fmt.Errorf("invalid filename"), actualError)
+}
+
+func TestParseRepeatableFileName(t *testing.T) {
+	tests := []struct {
+		input               string
+		expectedDescription string
+		expectedError       error
+	}{
+		{"R__create_views.sql", "create_views", nil},
+		{"R__create__views.sql", "create__views", nil},
+		{"R_create_views.sql", "", fmt.Errorf("invalid filename")},
+		{"R1__create_views.sql", "", fmt.Errorf("invalid filename")},
+	}
+	for _, test := range tests {
+		actualDescription, actualError := parseRepeatableFileName(test.input)
+		assert.Equal(t, test.expectedDescription, actualDescription)
+		assert.Equal(t, test.expectedError, actualError)
+	}
+}
+
+func TestLoad_whenValid_thenReturnMigrationsWithUndoContent(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/V1__create_table.sql":  {Data: []byte("create table test ( id int );")},
+		"migrations/U1__create_table.sql":  {Data: []byte("drop table test;")},
+		"migrations/V2__add_column.sql":    {Data: []byte("alter table test add column num int;")},
+		"migrations/R__create_views.sql":   {Data: []byte("create or replace view test_view as select id from test;")},
+		"migrations/README.md":             {Data: []byte("not a migration")},
+		"migrations/nested/V3__nested.sql": {Data: []byte("select 1;")},
+	}
+	migrations, err := New(fsys, "migrations").Load()
+	assert.Nil(t, err)
+	assert.Equal(t, []*migrsrc.Migration{
+		{Description: "create_views", Content: "create or replace view test_view as select id from test;", Kind: migrsrc.KindRepeatable, Source: "migrations/R__create_views.sql"},
+		{Version: "1", Description: "create_table", Content: "create table test ( id int );", UndoContent: "drop table test;", Source: "migrations/V1__create_table.sql"},
+		{Version: "2", Description: "add_column", Content: "alter table test add column num int;", Source: "migrations/V2__add_column.sql"},
+	}, migrations)
+}
+
+func TestLoad_whenRecursive_thenReturnMigrationsFromSubdirectories(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/V1__create_table.sql":  {Data: []byte("create table test ( id int );")},
+		"migrations/nested/V3__nested.sql": {Data: []byte("select 1;")},
+	}
+	migrations, err := New(fsys, "migrations", WithRecursive()).Load()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(migrations))
+	assert.Equal(t, "migrations/nested/V3__nested.sql", migrations[1].Source)
+}
+
+func TestLoad_whenDuplicateVersion_thenReturnErrorWithBothFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a/V1__create_table.sql": {Data: []byte("create table a ( id int );")},
+		"b/V1__create_table.sql": {Data: []byte("create table b ( id int );")},
+	}
+	_, err := New(fsys, ".", WithRecursive()).Load()
+	var duplicateErr *migrsrc.DuplicateError
+	assert.ErrorAs(t, err, &duplicateErr)
+	assert.Equal(t, "encountered duplicate version: 1 (a/V1__create_table.sql and b/V1__create_table.sql)", err.Error())
+}
+
+func TestLoad_whenUndoHasNoMigration_thenReturnError(t *testing.T) {
+	fsys := fstest.MapFS{
+		"V1__create_table.sql": {Data: []byte("create table test ( id int );")},
+		"U2__add_column.sql":   {Data: []byte("alter table test drop column num;")},
+	}
+	_, err := New(fsys, ".").Load()
+	assert.ErrorIs(t, err, ErrUnmatchedUndo)
+}
+
+func TestLoad_whenNoTransactionDirective_thenSetNoTransaction(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/V1__create_index.sql": {Data: []byte("-- going:no-transaction\ncreate index concurrently test_idx on test (id);")},
+		"migrations/V2__add_column.sql":   {Data: []byte("alter table test add column num int;")},
+	}
+	migrations, err := New(fsys, "migrations").Load()
+	assert.Nil(t, err)
+	assert.True(t, migrations[0].NoTransaction)
+	assert.False(t, migrations[1].NoTransaction)
+}
@@ -1,21 +1,70 @@
 package migrsrc
 
-import "fmt"
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/mlu1109/going/version"
+)
+
+type Kind int
+
+const (
+	KindVersioned Kind = iota
+	// KindRepeatable migrations have no version and are re-applied after all
+	// versioned migrations whenever their checksum changes.
+	KindRepeatable
+)
+
+// Func is a migration written in Go. It runs in the same transaction as the
+// history table update.
+type Func func(ctx context.Context, tx *sql.Tx) error
 
 type Migration struct {
-	Version     uint
+	Version     version.Version
 	Description string
 	Content     string
+	UndoContent string
+	// Func is run instead of Content if set. Content, e.g. a revision, is
+	// still checksummed and may be used to mark a changed Func. Without
+	// Content, version and description are checksummed.
+	Func Func
+	// NoTransaction migrations are executed outside of the transaction, e.g.
+	// for create index concurrently. See DirectiveNoTransaction.
+	NoTransaction bool
+	Kind          Kind
+	// Source is the file the migration was loaded from, if any.
+	Source string
+}
+
+func NewMigration(v version.Version, description, content string) *Migration {
+	return &Migration{
+		Version:     v,
+		Description: description,
+		Content:     content,
+	}
+}
+
+func NewFuncMigration(v version.Version, description string, fn Func) *Migration {
+	return &Migration{
+		Version:     v,
+		Description: description,
+		Func:        fn,
+	}
 }
 
-func NewMigration(version uint, description, content string) *Migration {
+func NewRepeatableMigration(description, content string) *Migration {
 	return &Migration{
-		Version:     version,
 		Description: description,
 		Content:     content,
+		Kind:        KindRepeatable,
 	}
 }
 
 func (m *Migration) String() string {
-	return fmt.Sprintf("V%d: %s", m.Version, m.Description)
+	if m.Kind == KindRepeatable {
+		return fmt.Sprintf("R: %s", m.Description)
+	}
+	return fmt.Sprintf("V%s: %s", m.Version, m.Description)
 }
@@ -3,3 +3,29 @@ package migrsrc
 type MS interface {
 	Load() ([]*Migration, error)
 }
+
+type combined struct {
+	sources []MS
+}
+
+// Combine returns an MS loading the migrations of all sources, e.g. SQL files
+// and Go migrations. Versions must be unique across sources.
+func Combine(sources ...MS) MS {
+	return &combined{sources}
+}
+
+func (c *combined) Load() ([]*Migration, error) {
+	var migrations []*Migration
+	for _, source := range c.sources {
+		loaded, err := source.Load()
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, loaded...)
+	}
+	err := CheckDuplicates(migrations)
+	if err != nil {
+		return nil, err
+	}
+	return migrations, nil
+}
@@ -0,0 +1,41 @@
+package registry
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/mlu1109/going/migrsrc"
+	"github.com/mlu1109/going/version"
+)
+
+// MS holds Go migrations registered by version.
+type MS struct {
+	migrations []*migrsrc.Migration
+}
+
+func New() *MS {
+	return &MS{}
+}
+
+// Register adds a Go migration. The caller's file and line is recorded as the
+// migration source. An optional revision is checksummed in place of version
+// and description, so changing it marks a changed fn.
+func (r *MS) Register(v version.Version, description string, fn migrsrc.Func, revision ...string) *MS {
+	m := migrsrc.NewFuncMigration(v, description, fn)
+	if len(revision) > 0 {
+		m.Content = revision[0]
+	}
+	if _, file, line, ok := runtime.Caller(1); ok {
+		m.Source = fmt.Sprintf("%s:%d", file, line)
+	}
+	r.migrations = append(r.migrations, m)
+	return r
+}
+
+func (r *MS) Load() ([]*migrsrc.Migration, error) {
+	err := migrsrc.CheckDuplicates(r.migrations)
+	if err != nil {
+		return nil, err
+	}
+	return r.migrations, nil
+}
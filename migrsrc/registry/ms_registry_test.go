@@ -0,0 +1,46 @@
+package registry
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/mlu1109/going/migrsrc"
+	"github.com/mlu1109/going/migrsrc/slice"
+	"github.com/mlu1109/going/version"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func noop(ctx context.Context, tx *sql.Tx) error {
+	return nil
+}
+
+func TestLoad_whenCombinedWithSQL_thenReturnAllMigrations(t *testing.T) {
+	r := New().Register("2", "backfill", noop)
+	ms := migrsrc.Combine(slice.New([]*migrsrc.Migration{
+		migrsrc.NewMigration("1", "create_table", "create table test ( id int );"),
+	}), r)
+	migrations, err := ms.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(migrations))
+	assert.Equal(t, version.Version("2"), migrations[1].Version)
+	assert.NotNil(t, migrations[1].Func)
+	assert.Contains(t, migrations[1].Source, "ms_registry_test.go")
+}
+
+func TestLoad_whenDuplicateVersion_thenReturnError(t *testing.T) {
+	r := New().Register("1", "backfill", noop)
+	ms := migrsrc.Combine(slice.New([]*migrsrc.Migration{
+		migrsrc.NewMigration("1", "create_table", "create table test ( id int );"),
+	}), r)
+	_, err := ms.Load()
+	var duplicateErr *migrsrc.DuplicateError
+	assert.ErrorAs(t, err, &duplicateErr)
+}
+
+func TestRegister_whenRevision_thenSetContent(t *testing.T) {
+	migrations, err := New().Register("1", "backfill", noop, "2").Load()
+	assert.Nil(t, err)
+	assert.Equal(t, "2", migrations[0].Content)
+}
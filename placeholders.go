@@ -0,0 +1,46 @@
+package going
+
+import (
+	"regexp"
+
+	"github.com/mlu1109/going/migrsrc"
+)
+
+var placeholderMatcher = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// expandPlaceholders returns copies of migrations with placeholders replaced
+// in their content and undo content.
+func expandPlaceholders(migrations []*migrsrc.Migration, placeholders map[string]string) ([]*migrsrc.Migration, error) {
+	expanded := make([]*migrsrc.Migration, 0, len(migrations))
+	for _, m := range migrations {
+		content, err := expandPlaceholdersInContent(m, m.Content, placeholders)
+		if err != nil {
+			return nil, err
+		}
+		undoContent, err := expandPlaceholdersInContent(m, m.UndoContent, placeholders)
+		if err != nil {
+			return nil, err
+		}
+		c := *m
+		c.Content = content
+		c.UndoContent = undoContent
+		expanded = append(expanded, &c)
+	}
+	return expanded, nil
+}
+
+func expandPlaceholdersInContent(m *migrsrc.Migration, content string, placeholders map[string]string) (string, error) {
+	var err error
+	expanded := placeholderMatcher.ReplaceAllStringFunc(content, func(match string) string {
+		name := placeholderMatcher.FindStringSubmatch(match)[1]
+		value, ok := placeholders[name]
+		if !ok && err == nil {
+			err = &UnresolvedPlaceholderError{Placeholder: name, Migration: m}
+		}
+		return value
+	})
+	if err != nil {
+		return "", err
+	}
+	return expanded, nil
+}
@@ -0,0 +1,30 @@
+package going
+
+import (
+	"github.com/mlu1109/going/migrsrc"
+	"github.com/mlu1109/going/version"
+)
+
+type Plan struct {
+	Steps []*PlanStep
+}
+
+type PlanStep struct {
+	Version     version.Version
+	Description string
+	Checksum    string
+	Content     string
+	Kind        migrsrc.Kind
+}
+
+// Versions returns the versions of the versioned migrations in the plan.
+func (p *Plan) Versions() []version.Version {
+	versions := make([]version.Version, 0, len(p.Steps))
+	for _, s := range p.Steps {
+		if s.Kind == migrsrc.KindRepeatable {
+			continue
+		}
+		versions = append(versions, s.Version)
+	}
+	return versions
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/mlu1109/going/datasrc"
 	"github.com/mlu1109/going/migrsrc"
+	"github.com/mlu1109/going/version"
 )
 
 func findMigration(migrations []*datasrc.Migration, predicate func(*datasrc.Migration) bool) *datasrc.Migration {
@@ -17,37 +18,163 @@ func findMigration(migrations []*datasrc.Migration, predicate func(*datasrc.Migr
 	return nil
 }
 
-func getDatasrcMigrationMappedByVersion(migrations []*datasrc.Migration) (map[uint]*datasrc.Migration, error) {
-	res := make(map[uint]*datasrc.Migration)
+func getDatasrcMigrationMappedByVersion(migrations []*datasrc.Migration) (map[version.Version]*datasrc.Migration, error) {
+	res := make(map[version.Version]*datasrc.Migration)
 	for _, m := range migrations {
+		if m.Kind == datasrc.KindRepeatable {
+			continue
+		}
 		_, ok := res[m.Version]
 		if ok {
-			return nil, fmt.Errorf("encountered duplicate version: %d", m.Version)
+			return nil, fmt.Errorf("encountered duplicate version: %s", m.Version)
 		}
 		res[m.Version] = m
 	}
 	return res, nil
 }
 
-func getLocalMigrationsMappedByVersion(migrations []*migrsrc.Migration) (map[uint]*migrsrc.Migration, error) {
-	res := make(map[uint]*migrsrc.Migration)
+func getLocalMigrationsMappedByVersion(migrations []*migrsrc.Migration) (map[version.Version]*migrsrc.Migration, error) {
+	res := make(map[version.Version]*migrsrc.Migration)
 	for _, m := range migrations {
+		if m.Kind == migrsrc.KindRepeatable {
+			continue
+		}
 		_, ok := res[m.Version]
 		if ok {
-			return nil, fmt.Errorf("encountered duplicate version: %d", m.Version)
+			return nil, fmt.Errorf("encountered duplicate version: %s", m.Version)
 		}
 		res[m.Version] = m
 	}
 	return res, nil
 }
 
-func getKeysSorted(keyValues map[uint]*migrsrc.Migration) []uint {
-	var keys []uint
+func getKeysSorted(keyValues map[version.Version]*migrsrc.Migration) []version.Version {
+	var keys []version.Version
+	for k, _ := range keyValues {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Less(keys[j])
+	})
+	return keys
+}
+
+func getAppliedKeysSorted(keyValues map[version.Version]*datasrc.Migration) []version.Version {
+	var keys []version.Version
 	for k, _ := range keyValues {
 		keys = append(keys, k)
 	}
 	sort.Slice(keys, func(i, j int) bool {
-		return keys[i] < keys[j]
+		return keys[i].Less(keys[j])
+	})
+	return keys
+}
+
+func getVersionsUpTo(versions []version.Version, target version.Version) []version.Version {
+	res := make([]version.Version, 0)
+	for _, v := range versions {
+		if v.Compare(target) <= 0 {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+func getAllKeysSorted(local map[version.Version]*migrsrc.Migration, applied map[version.Version]*datasrc.Migration) []version.Version {
+	keys := getKeysSorted(local)
+	for k, _ := range applied {
+		if _, ok := local[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Less(keys[j])
 	})
 	return keys
 }
+
+func getLatestAppliedVersion(applied map[version.Version]*datasrc.Migration) version.Version {
+	var latest version.Version
+	for v := range applied {
+		if latest.Less(v) {
+			latest = v
+		}
+	}
+	return latest
+}
+
+func getBaselineVersion(applied map[version.Version]*datasrc.Migration) (version.Version, bool) {
+	for v, m := range applied {
+		if m.Kind == datasrc.KindBaseline {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func getLatestRepeatableMigrationsMappedByDescription(migrations []*datasrc.Migration) map[string]*datasrc.Migration {
+	res := make(map[string]*datasrc.Migration)
+	for _, m := range migrations {
+		if m.Kind == datasrc.KindRepeatable {
+			res[m.Description] = m
+		}
+	}
+	return res
+}
+
+func getLocalRepeatableMigrationsSorted(migrations []*migrsrc.Migration) ([]*migrsrc.Migration, error) {
+	res := make([]*migrsrc.Migration, 0)
+	seen := make(map[string]bool)
+	for _, m := range migrations {
+		if m.Kind != migrsrc.KindRepeatable {
+			continue
+		}
+		if seen[m.Description] {
+			return nil, fmt.Errorf("encountered duplicate repeatable migration: %s", m.Description)
+		}
+		seen[m.Description] = true
+		res = append(res, m)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Description < res[j].Description
+	})
+	return res, nil
+}
+
+// getMigrationsWithCanonicalVersions returns migrations with their versions in
+// canonical form, copying the migrations that were not.
+func getMigrationsWithCanonicalVersions(migrations []*migrsrc.Migration) ([]*migrsrc.Migration, error) {
+	res := make([]*migrsrc.Migration, 0, len(migrations))
+	for _, m := range migrations {
+		if m.Kind == migrsrc.KindRepeatable {
+			res = append(res, m)
+			continue
+		}
+		v, err := version.Parse(string(m.Version))
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", m, err)
+		}
+		if v != m.Version {
+			c := *m
+			c.Version = v
+			m = &c
+		}
+		res = append(res, m)
+	}
+	return res, nil
+}
+
+func getMigrationsByVersions(local map[version.Version]*migrsrc.Migration, versions []version.Version) []*migrsrc.Migration {
+	res := make([]*migrsrc.Migration, 0, len(versions))
+	for _, v := range versions {
+		res = append(res, local[v])
+	}
+	return res
+}
+
+func getDatasrcKind(m *migrsrc.Migration) datasrc.Kind {
+	if m.Kind == migrsrc.KindRepeatable {
+		return datasrc.KindRepeatable
+	}
+	return datasrc.KindVersioned
+}
@@ -0,0 +1,108 @@
+package version
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a migration version of numeric parts separated by dots or
+// underscores, e.g. 1, 1.2.1, 1_2_1 or a timestamp such as 20261018143000.
+// It is kept in a canonical form, with dots, without leading zeros and without
+// trailing zero parts, so that equal versions are == and can be map keys. The
+// zero value is no version, as for repeatable migrations.
+type Version string
+
+var ErrInvalid = errors.New("invalid version")
+
+// Parse returns the canonical form of s.
+func Parse(s string) (Version, error) {
+	if s == "" {
+		return "", ErrInvalid
+	}
+	parts := strings.FieldsFunc(s, func(r rune) bool {
+		return r == '.' || r == '_'
+	})
+	if len(parts) == 0 || strings.Count(s, ".")+strings.Count(s, "_") != len(parts)-1 {
+		return "", fmt.Errorf("%w: %s", ErrInvalid, s)
+	}
+	canonical := make([]string, 0, len(parts))
+	for _, p := range parts {
+		n, err := strconv.ParseUint(p, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s", ErrInvalid, s)
+		}
+		canonical = append(canonical, strconv.FormatUint(n, 10))
+	}
+	for len(canonical) > 1 && canonical[len(canonical)-1] == "0" {
+		canonical = canonical[:len(canonical)-1]
+	}
+	return Version(strings.Join(canonical, ".")), nil
+}
+
+// MustParse is like Parse but panics if s is invalid.
+func MustParse(s string) Version {
+	v, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// FromUint returns the version of a single number.
+func FromUint(n uint) Version {
+	return Version(strconv.FormatUint(uint64(n), 10))
+}
+
+// Compare returns -1, 0 or 1 if v is lower than, equal to or higher than o,
+// comparing part by part. No version is lower than any version.
+func (v Version) Compare(o Version) int {
+	a, b := v.parts(), o.parts()
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y uint64
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	switch {
+	case v == "" && o != "":
+		return -1
+	case v != "" && o == "":
+		return 1
+	}
+	return 0
+}
+
+func (v Version) Less(o Version) bool {
+	return v.Compare(o) < 0
+}
+
+func (v Version) String() string {
+	return string(v)
+}
+
+// parts expects v to be in canonical form, see Parse.
+func (v Version) parts() []uint64 {
+	if v == "" {
+		return nil
+	}
+	fields := strings.FieldsFunc(string(v), func(r rune) bool {
+		return r == '.' || r == '_'
+	})
+	parts := make([]uint64, 0, len(fields))
+	for _, f := range fields {
+		n, _ := strconv.ParseUint(f, 10, 64)
+		parts = append(parts, n)
+	}
+	return parts
+}
@@ -0,0 +1,39 @@
+package version
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse_whenValid_thenReturnCanonicalVersion(t *testing.T) {
+	for s, expected := range map[string]Version{
+		"1":              "1",
+		"001":            "1",
+		"1.2.1":          "1.2.1",
+		"1_2_1":          "1.2.1",
+		"1.0.0":          "1",
+		"1.0.2":          "1.0.2",
+		"20261018143000": "20261018143000",
+	} {
+		v, err := Parse(s)
+		assert.Nil(t, err, s)
+		assert.Equal(t, expected, v, s)
+	}
+}
+
+func TestParse_whenInvalid_thenReturnError(t *testing.T) {
+	for _, s := range []string{"", "a", "1..2", "1.", ".1", "1.a", "-1"} {
+		_, err := Parse(s)
+		assert.ErrorIs(t, err, ErrInvalid, s)
+	}
+}
+
+func TestCompare_whenDifferentLengths_thenComparePartByPart(t *testing.T) {
+	assert.Equal(t, -1, MustParse("1.2").Compare(MustParse("1.10")))
+	assert.Equal(t, -1, MustParse("2").Compare(MustParse("10")))
+	assert.Equal(t, 1, MustParse("1.2.1").Compare(MustParse("1.2")))
+	assert.Equal(t, 0, MustParse("1.2").Compare(MustParse("1.2.0")))
+	assert.Equal(t, 1, MustParse("20261018143000").Compare(MustParse("2")))
+	assert.Equal(t, -1, Version("").Compare(MustParse("0")))
+}